@@ -0,0 +1,343 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type Activation string
+
+const (
+	ActivationSymlink  Activation = "symlink"
+	ActivationHardlink Activation = "hardlink"
+	ActivationCopy     Activation = "copy"
+)
+
+const activationManifestFile = "activated.json"
+
+var ErrLinkConflict = errors.New("Mods folder has entries in the way of mods")
+
+func (config Config) ActivationStrategy() (Activation, error) {
+	switch Activation(config.Activation) {
+	case "", ActivationSymlink:
+		return ActivationSymlink, nil
+	case ActivationHardlink:
+		return ActivationHardlink, nil
+	case ActivationCopy:
+		return ActivationCopy, nil
+	}
+	return "", fmt.Errorf("unknown activation %q, expected symlink, hardlink or copy", config.Activation)
+}
+
+// entries rimtag created in each Mods folder and how, so nothing else in there is ever removed.
+// see ownsEntry for when an entry still counts as ours
+type activationManifest map[string]map[string]Activation
+
+func manifestPath() string {
+	return filepath.Join(GetConfigPath(), activationManifestFile)
+}
+
+func loadActivationManifest() (activationManifest, error) {
+	manifest := activationManifest{}
+	data, err := os.ReadFile(manifestPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func saveActivationManifest(manifest activationManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(manifestPath(), data, 0644)
+}
+
+// whether the symlink at path points into SteamModSrc or LocalModSrc
+func linksIntoModSources(path string, config Config) bool {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return false
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	for _, src := range []string{config.SteamModSrc, config.LocalModSrc} {
+		if src == "" {
+			continue
+		}
+		rel, err := filepath.Rel(filepath.Clean(src), filepath.Clean(target))
+		if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// an entry is rimtag's only while it still is what was recorded: a symlink for ActivationSymlink,
+// a real directory for copies and hardlinks. unrecorded symlinks into the mod sources are from older versions
+func ownsEntry(recorded Activation, isRecorded bool, info fs.FileInfo, path string, config Config) bool {
+	isLink := info.Mode()&os.ModeSymlink != 0
+	if !isRecorded {
+		return isLink && linksIntoModSources(path, config)
+	}
+	if recorded == ActivationSymlink {
+		return isLink
+	}
+	return info.IsDir()
+}
+
+type ActivationSummary struct {
+	Added   []string
+	Removed []string
+	Kept    []string
+	// entries in Mods that rimtag didn't create but have the name of a mod to activate, these are never touched
+	Conflicts []string
+}
+
+func (summary ActivationSummary) String() string {
+	lines := []string{fmt.Sprintf(
+		"%d mods added, %d removed, %d kept, %d conflicts",
+		len(summary.Added), len(summary.Removed), len(summary.Kept), len(summary.Conflicts),
+	)}
+	for _, name := range summary.Added {
+		lines = append(lines, "  + "+name)
+	}
+	for _, name := range summary.Removed {
+		lines = append(lines, "  - "+name)
+	}
+	for _, name := range summary.Conflicts {
+		lines = append(lines, "  ! "+name+" was not created by rimtag, leaving it alone")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// makes TargetDir/Mods match mods using the configured activation: missing mods are added,
+// entries rimtag created for mods no longer in the list (or with another activation) are removed,
+// and copies are synced incrementally. with dryRun nothing is changed, but the summary is what would have happened
+func ActivateMods(mods []*Mod, config Config, dryRun bool) (ActivationSummary, error) {
+	modsDir := filepath.Join(config.TargetDir, "Mods")
+	summary := ActivationSummary{Added: []string{}, Removed: []string{}, Kept: []string{}, Conflicts: []string{}}
+
+	strategy, err := config.ActivationStrategy()
+	if err != nil {
+		return summary, err
+	}
+	manifest, err := loadActivationManifest()
+	if err != nil {
+		return summary, err
+	}
+	created := manifest[modsDir]
+	if created == nil {
+		created = map[string]Activation{}
+	}
+
+	desired := map[string]string{}
+	for _, mod := range mods {
+		if mod.Source == ModSourceOfficial {
+			continue
+		}
+		desired[string(mod.PackageID)] = mod.Path
+	}
+
+	entries, err := os.ReadDir(modsDir)
+	if err != nil {
+		return summary, err
+	}
+	errs := []error{}
+	present := map[string]bool{}
+	owned := map[string]Activation{}
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(modsDir, name)
+		info, err := os.Lstat(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		isLink := info.Mode()&os.ModeSymlink != 0
+		source, wanted := desired[name]
+		recorded, isRecorded := created[name]
+		if !ownsEntry(recorded, isRecorded, info, path, config) {
+			// a recorded entry the user has since replaced is theirs now, and is reported once before it is forgotten
+			if wanted || isRecorded {
+				summary.Conflicts = append(summary.Conflicts, name)
+				present[name] = true
+			}
+			continue
+		}
+
+		if wanted {
+			keep := false
+			switch {
+			case strategy == ActivationSymlink && isLink:
+				current, err := os.Readlink(path)
+				keep = err == nil && current == source
+			case strategy != ActivationSymlink && !isLink && created[name] == strategy:
+				keep = true
+				if !dryRun {
+					if err := syncTree(source, path, strategy == ActivationHardlink); err != nil {
+						errs = append(errs, fmt.Errorf("%s: %w", name, err))
+					}
+				}
+			}
+			if keep {
+				summary.Kept = append(summary.Kept, name)
+				present[name] = true
+				owned[name] = strategy
+				continue
+			}
+		}
+
+		summary.Removed = append(summary.Removed, name)
+		if dryRun {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			errs = append(errs, err)
+			present[name] = true
+			owned[name] = created[name]
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(desired)) {
+		if present[name] {
+			continue
+		}
+		summary.Added = append(summary.Added, name)
+		if dryRun {
+			continue
+		}
+		path := filepath.Join(modsDir, name)
+		if strategy == ActivationSymlink {
+			err = os.Symlink(desired[name], path)
+		} else {
+			err = syncTree(desired[name], path, strategy == ActivationHardlink)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		owned[name] = strategy
+	}
+
+	if !dryRun {
+		manifest[modsDir] = owned
+		if err := saveActivationManifest(manifest); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(summary.Conflicts) > 0 {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrLinkConflict, strings.Join(summary.Conflicts, ", ")))
+	}
+	return summary, errors.Join(errs...)
+}
+
+// makes dst a copy (or tree of hardlinks) of src, only touching files whose size or mtime differ
+// and removing anything src no longer has. .git folders are skipped
+func syncTree(src string, dst string, hardlink bool) error {
+	wanted := map[string]bool{}
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		wanted[rel] = true
+
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if existing, err := os.Lstat(target); err == nil {
+			if hardlink && os.SameFile(info, existing) {
+				return nil
+			}
+			if !hardlink && existing.Mode().IsRegular() && existing.Size() == info.Size() && existing.ModTime().Equal(info.ModTime()) {
+				return nil
+			}
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+		}
+		if hardlink {
+			return os.Link(path, target)
+		}
+		return copyFile(path, target, info)
+	})
+	if err != nil {
+		return err
+	}
+
+	stale := []string{}
+	err = filepath.WalkDir(dst, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dst, path)
+		if err != nil {
+			return err
+		}
+		if !wanted[rel] {
+			stale = append(stale, path)
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, path := range stale {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(src string, dst string, info fs.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
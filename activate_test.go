@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// a mod folder in LocalModSrc and an empty Mods folder, with the manifest in a scratch config directory
+func activationFixture(t *testing.T, activation Activation) (*Mod, Config) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	config := Config{
+		LocalModSrc: filepath.Join(dir, "local"),
+		TargetDir:   filepath.Join(dir, "game"),
+		Activation:  string(activation),
+	}
+	mod := &Mod{PackageID: "foo", Source: ModSourceLocal, Path: filepath.Join(config.LocalModSrc, "foo")}
+	for _, path := range []string{GetConfigPath(), filepath.Join(mod.Path, "About"), filepath.Join(config.TargetDir, "Mods")} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(mod.Path, "About", "About.xml"), []byte("<ModMetaData/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return mod, config
+}
+
+// replaces Mods/foo with something of the user's
+func replaceEntry(t *testing.T, config Config, replacement func(path string) error) string {
+	t.Helper()
+	path := filepath.Join(config.TargetDir, "Mods", "foo")
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := replacement(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestActivateModsKeepsReplacedSymlink(t *testing.T) {
+	mod, config := activationFixture(t, ActivationSymlink)
+	if _, err := ActivateMods([]*Mod{mod}, config, false); err != nil {
+		t.Fatal(err)
+	}
+
+	path := replaceEntry(t, config, func(path string) error {
+		if err := os.Mkdir(path, 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(path, "precious.txt"), []byte("mine"), 0644)
+	})
+
+	for _, mods := range [][]*Mod{{mod}, {}} {
+		summary, err := ActivateMods(mods, config, false)
+		if len(mods) > 0 && !errors.Is(err, ErrLinkConflict) {
+			t.Errorf("expected ErrLinkConflict, got %v", err)
+		}
+		if len(summary.Removed) > 0 || len(summary.Added) > 0 {
+			t.Errorf("touched the user's directory: %s", summary)
+		}
+		if _, err := os.Stat(filepath.Join(path, "precious.txt")); err != nil {
+			t.Fatalf("user's file is gone: %v", err)
+		}
+	}
+}
+
+func TestActivateModsKeepsReplacedCopy(t *testing.T) {
+	mod, config := activationFixture(t, ActivationCopy)
+	if _, err := ActivateMods([]*Mod{mod}, config, false); err != nil {
+		t.Fatal(err)
+	}
+
+	elsewhere := t.TempDir()
+	path := replaceEntry(t, config, func(path string) error {
+		return os.Symlink(elsewhere, path)
+	})
+
+	summary, err := ActivateMods([]*Mod{mod}, config, false)
+	if !errors.Is(err, ErrLinkConflict) {
+		t.Errorf("expected ErrLinkConflict, got %v", err)
+	}
+	if !slices.Contains(summary.Conflicts, "foo") {
+		t.Errorf("foo not reported as a conflict: %s", summary)
+	}
+	if target, err := os.Readlink(path); err != nil || target != elsewhere {
+		t.Errorf("user's symlink was changed: %q, %v", target, err)
+	}
+}
+
+func TestActivateModsRemovesOwnSymlink(t *testing.T) {
+	mod, config := activationFixture(t, ActivationSymlink)
+	if _, err := ActivateMods([]*Mod{mod}, config, false); err != nil {
+		t.Fatal(err)
+	}
+	summary, err := ActivateMods([]*Mod{}, config, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(summary.Removed, []string{"foo"}) {
+		t.Errorf("removed %v, want [foo]", summary.Removed)
+	}
+	if _, err := os.Lstat(filepath.Join(config.TargetDir, "Mods", "foo")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Mods/foo still exists: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+type DepTree struct {
+	// every mod that may appear in the tree, the list or all installed mods
+	Mods   map[PackageID]*Mod
+	Active map[PackageID]bool
+	// whether Mods has every installed mod, so anything else is not installed
+	AllInstalled bool
+	lines        []string
+	seen         map[PackageID]bool
+}
+
+func NewDepTree(universe []*Mod, active []*Mod, allInstalled bool) *DepTree {
+	tree := &DepTree{Mods: map[PackageID]*Mod{}, Active: map[PackageID]bool{}, AllInstalled: allInstalled}
+	for _, mod := range active {
+		tree.Mods[mod.PackageID] = mod
+		tree.Active[mod.PackageID] = true
+	}
+	for _, mod := range universe {
+		if _, ok := tree.Mods[mod.PackageID]; !ok {
+			tree.Mods[mod.PackageID] = mod
+		}
+	}
+	return tree
+}
+
+func (tree *DepTree) label(pid PackageID) string {
+	if _, ok := tree.Mods[pid]; !ok && tree.AllInstalled {
+		return string(pid) + " (not installed)"
+	}
+	if !tree.Active[pid] {
+		return string(pid) + " (not in list)"
+	}
+	return string(pid)
+}
+
+func (tree *DepTree) add(depth int, line string) {
+	tree.lines = append(tree.lines, strings.Repeat("  ", depth)+line)
+}
+
+// expands pid unless it is already on the current path or was expanded earlier
+func (tree *DepTree) visit(pid PackageID, depth int, path []PackageID, note string, expand func(PackageID, int, []PackageID)) {
+	switch {
+	case slices.Contains(path, pid):
+		tree.add(depth, tree.label(pid)+note+" (cycle)")
+	case tree.seen[pid]:
+		tree.add(depth, tree.label(pid)+note+" (see above)")
+	default:
+		tree.add(depth, tree.label(pid)+note)
+		tree.seen[pid] = true
+		expand(pid, depth+1, append(path, pid))
+	}
+}
+
+// transitive dependencies of pid, alternatives grouped under "one of"
+func (tree *DepTree) Of(pid PackageID) string {
+	tree.lines = []string{}
+	tree.seen = map[PackageID]bool{}
+	var expand func(PackageID, int, []PackageID)
+	expand = func(pid PackageID, depth int, path []PackageID) {
+		mod, ok := tree.Mods[pid]
+		if !ok {
+			return
+		}
+		for _, group := range mod.Deps {
+			if len(group) == 1 {
+				tree.visit(group[0], depth, path, "", expand)
+				continue
+			}
+			tree.add(depth, "one of:")
+			for _, alternative := range group {
+				tree.visit(alternative, depth+1, path, "", expand)
+			}
+		}
+	}
+	tree.visit(pid, 0, []PackageID{}, "", expand)
+	return strings.Join(tree.lines, "\n")
+}
+
+// transitive dependents of pid, noting when a dependent could use something else instead
+func (tree *DepTree) On(pid PackageID) string {
+	tree.lines = []string{}
+	tree.seen = map[PackageID]bool{}
+	dependents := map[PackageID][]*Mod{}
+	groups := map[PackageID]map[*Mod][]PackageID{}
+	for _, pid := range slices.Sorted(maps.Keys(tree.Mods)) {
+		mod := tree.Mods[pid]
+		for _, group := range mod.Deps {
+			for _, dep := range group {
+				if groups[dep] == nil {
+					groups[dep] = map[*Mod][]PackageID{}
+				}
+				if _, ok := groups[dep][mod]; !ok {
+					dependents[dep] = append(dependents[dep], mod)
+				}
+				groups[dep][mod] = group
+			}
+		}
+	}
+
+	var expand func(PackageID, int, []PackageID)
+	expand = func(pid PackageID, depth int, path []PackageID) {
+		for _, dependent := range dependents[pid] {
+			note := ""
+			if group := groups[pid][dependent]; len(group) > 1 {
+				alternatives := []string{}
+				for _, alternative := range group {
+					if alternative != pid {
+						alternatives = append(alternatives, tree.label(alternative))
+					}
+				}
+				note = fmt.Sprintf(" [or instead: %s]", strings.Join(alternatives, ", "))
+			}
+			tree.visit(dependent.PackageID, depth, path, note, expand)
+		}
+	}
+	tree.visit(pid, 0, []PackageID{}, "", expand)
+	return strings.Join(tree.lines, "\n")
+}
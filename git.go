@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s exited with code %d", args[0], exitErr.ExitCode())
+		} else {
+			return "", fmt.Errorf("failed to run git: %v", err)
+		}
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func GitHead(modPath string) (string, error) {
+	return runGit(modPath, "rev-parse", "HEAD")
+}
+
+// clones url into LocalModSrc, returning the path of the new mod
+func GitClone(config Config, url string) (string, error) {
+	name := strings.TrimSuffix(path.Base(strings.TrimRight(url, "/")), ".git")
+	if name == "" || name == "." || name == "/" {
+		return "", fmt.Errorf("can't name a mod folder after %q", url)
+	}
+	dest := filepath.Join(config.LocalModSrc, name)
+	if _, err := runGit(config.LocalModSrc, "clone", url, dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+type GitUpdate struct {
+	Mod    *Mod
+	Before string
+	After  string
+	Err    error
+}
+
+func (update GitUpdate) String() string {
+	switch {
+	case update.Err != nil:
+		return fmt.Sprintf("%s failed: %v", update.Mod.PackageID, update.Err)
+	case update.Before == update.After:
+		return fmt.Sprintf("%s unchanged at %.8s", update.Mod.PackageID, update.After)
+	default:
+		return fmt.Sprintf("%s %.8s -> %.8s", update.Mod.PackageID, update.Before, update.After)
+	}
+}
+
+// pulls every git mod, or only those in pids if any are given. every installed copy is looked at,
+// so a clone is pulled even when a local copy of the same mod is preferred.
+// also returns the pids that no git mod has
+func GitPull(config Config, pids []PackageID) ([]GitUpdate, []PackageID) {
+	updates := []GitUpdate{}
+	matched := map[PackageID]bool{}
+	for _, mod := range GetInstalledMods(config) {
+		if mod.Source != ModSourceGit {
+			continue
+		}
+		if len(pids) > 0 && !slices.Contains(pids, mod.PackageID) {
+			continue
+		}
+		matched[mod.PackageID] = true
+		update := GitUpdate{Mod: mod}
+		update.Before, update.Err = GitHead(mod.Path)
+		if update.Err == nil {
+			_, update.Err = runGit(mod.Path, "pull", "--ff-only")
+		}
+		if update.Err == nil {
+			update.After, update.Err = GitHead(mod.Path)
+		}
+		updates = append(updates, update)
+	}
+	unmatched := []PackageID{}
+	for _, pid := range pids {
+		if !matched[pid] && !slices.Contains(unmatched, pid) {
+			unmatched = append(unmatched, pid)
+		}
+	}
+	return updates, unmatched
+}
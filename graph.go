@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+type GraphLoadEdge struct {
+	PackageID PackageID    `json:"packageId"`
+	Forced    bool         `json:"forced"`
+	Origins   []EdgeOrigin `json:"origins"`
+}
+
+type GraphNode struct {
+	Name   string `json:"name,omitempty"`
+	Source string `json:"source"`
+	// false for dependencies that are not in the list
+	InList       bool            `json:"inList"`
+	LoadAfter    []GraphLoadEdge `json:"loadAfter"`
+	Dependencies [][]PackageID   `json:"dependencies"`
+}
+
+// adjacency document keyed by package ID, edges point from a mod to what it needs
+type Graph map[PackageID]*GraphNode
+
+// graph of LoadAfter edges from LinkMods and Deps, call after LinkMods.
+// the implicit edges from every mod to the Ludeon mods are left out unless includeCore is set
+func BuildGraph(mods []*Mod, includeCore bool) Graph {
+	graph := Graph{}
+	for _, mod := range mods {
+		node := &GraphNode{
+			Name:         mod.About.Name,
+			Source:       mod.Source.String(),
+			InList:       true,
+			LoadAfter:    []GraphLoadEdge{},
+			Dependencies: mod.Deps,
+		}
+		for _, target := range mod.LoadAfter {
+			origins := mod.LoadAfterOrigins[target]
+			if !includeCore && len(origins) == 1 && origins[0] == OriginCore {
+				continue
+			}
+			edge := GraphLoadEdge{PackageID: target.PackageID, Origins: origins}
+			for _, origin := range origins {
+				if origin.IsForced() {
+					edge.Forced = true
+				}
+			}
+			node.LoadAfter = append(node.LoadAfter, edge)
+		}
+		graph[mod.PackageID] = node
+	}
+	for _, mod := range mods {
+		for _, group := range mod.Deps {
+			for _, pid := range group {
+				if _, ok := graph[pid]; !ok {
+					graph[pid] = &GraphNode{Source: "missing", LoadAfter: []GraphLoadEdge{}, Dependencies: [][]PackageID{}}
+				}
+			}
+		}
+	}
+	return graph
+}
+
+// subgraph of everything within depth edges of pid, in either direction.
+// dependency groups are kept whole so alternatives stay recognisable
+func (graph Graph) Around(pid PackageID, depth int) Graph {
+	neighbours := map[PackageID][]PackageID{}
+	for from, node := range graph {
+		targets := []PackageID{}
+		for _, edge := range node.LoadAfter {
+			targets = append(targets, edge.PackageID)
+		}
+		for _, group := range node.Dependencies {
+			targets = append(targets, group...)
+		}
+		for _, to := range targets {
+			neighbours[from] = append(neighbours[from], to)
+			neighbours[to] = append(neighbours[to], from)
+		}
+	}
+
+	distance := map[PackageID]int{pid: 0}
+	queue := []PackageID{pid}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if distance[current] >= depth {
+			continue
+		}
+		for _, next := range neighbours[current] {
+			if _, seen := distance[next]; seen {
+				continue
+			}
+			distance[next] = distance[current] + 1
+			queue = append(queue, next)
+		}
+	}
+
+	sub := Graph{}
+	for pid := range distance {
+		node, ok := graph[pid]
+		if !ok {
+			continue
+		}
+		trimmed := *node
+		trimmed.LoadAfter = []GraphLoadEdge{}
+		for _, edge := range node.LoadAfter {
+			if _, ok := distance[edge.PackageID]; ok {
+				trimmed.LoadAfter = append(trimmed.LoadAfter, edge)
+			}
+		}
+		sub[pid] = &trimmed
+	}
+	return sub
+}
+
+func (graph Graph) JSON() (string, error) {
+	data, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// DOT strings only understand escaped quotes, backslashes and newlines, unlike %q
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// Graphviz export, arrows point from a mod to what it loads after or depends on:
+// loadAfter is grey, forced load order is bold red, a strict dependency is blue
+// and each alternative of a "one of" dependency is a dotted purple edge labelled with its group
+func (graph Graph) DOT() string {
+	lines := []string{
+		"digraph rimtag {",
+		"  rankdir=LR;",
+		"  node [shape=box];",
+	}
+	for _, pid := range slices.Sorted(maps.Keys(graph)) {
+		node := graph[pid]
+		label := string(pid)
+		if node.Name != "" {
+			label = node.Name + "\n" + string(pid)
+		}
+		attrs := "label=" + dotQuote(label)
+		if !node.InList {
+			attrs += ", style=dashed, color=red"
+		} else if node.Source == ModSourceOfficial.String() {
+			attrs += ", style=filled, fillcolor=lightgrey"
+		}
+		lines = append(lines, fmt.Sprintf("  %s [%s];", dotQuote(string(pid)), attrs))
+	}
+	for _, pid := range slices.Sorted(maps.Keys(graph)) {
+		node := graph[pid]
+		for _, edge := range node.LoadAfter {
+			style := "color=grey"
+			if edge.Forced {
+				style = "color=red, penwidth=2"
+			}
+			lines = append(lines, fmt.Sprintf("  %s -> %s [%s];", dotQuote(string(pid)), dotQuote(string(edge.PackageID)), style))
+		}
+		for i, group := range node.Dependencies {
+			if len(group) == 1 {
+				if _, ok := graph[group[0]]; ok {
+					lines = append(lines, fmt.Sprintf("  %s -> %s [color=blue];", dotQuote(string(pid)), dotQuote(string(group[0]))))
+				}
+				continue
+			}
+			for _, dep := range group {
+				if _, ok := graph[dep]; !ok {
+					continue
+				}
+				lines = append(lines, fmt.Sprintf("  %s -> %s [color=purple, style=dotted, label=\"one of #%d\"];", dotQuote(string(pid)), dotQuote(string(dep)), i+1))
+			}
+		}
+	}
+	lines = append(lines, "}")
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const historySize = 20
+const historyTimeFormat = "20060102-150405.000"
+
+// list name for ModsConfig.xml files rimtag didn't write
+const externalHistoryList = "external"
+
+type HistoryEntry struct {
+	Path string
+	Time time.Time
+	// list that was loaded to produce this ModsConfig.xml
+	List string
+}
+
+func GetHistoryPath() string {
+	return filepath.Join(GetConfigPath(), "history")
+}
+
+func GetModsConfigPath(config Config) string {
+	return filepath.Join(config.RimworldData, "Config/ModsConfig.xml")
+}
+
+// history entries, newest first
+func ListHistory() ([]HistoryEntry, error) {
+	files, err := os.ReadDir(GetHistoryPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return []HistoryEntry{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	entries := []HistoryEntry{}
+	for _, file := range files {
+		name, ok := strings.CutSuffix(file.Name(), ".xml")
+		if !ok {
+			continue
+		}
+		stamp, list, ok := strings.Cut(name, "--")
+		if !ok {
+			continue
+		}
+		t, err := time.ParseInLocation(historyTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, HistoryEntry{Path: filepath.Join(GetHistoryPath(), file.Name()), Time: t, List: list})
+	}
+	slices.SortFunc(entries, func(a HistoryEntry, b HistoryEntry) int {
+		return b.Time.Compare(a.Time)
+	})
+	return entries, nil
+}
+
+func recordHistory(data []byte, list string) error {
+	if err := os.MkdirAll(GetHistoryPath(), 0755); err != nil {
+		return err
+	}
+	list = strings.NewReplacer("/", "_", `\`, "_").Replace(list)
+	name := time.Now().Format(historyTimeFormat) + "--" + list + ".xml"
+	if err := os.WriteFile(filepath.Join(GetHistoryPath(), name), data, 0644); err != nil {
+		return err
+	}
+
+	entries, err := ListHistory()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries[min(len(entries), historySize):] {
+		if err := os.Remove(entry.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// temp file and rename, so the game never sees a half-written file
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// writes ModsConfig.xml and records it in the history under list.
+// if the current file isn't the last one rimtag wrote, it is kept in the history first
+func WriteModsConfig(data []byte, config Config, list string) error {
+	path := GetModsConfigPath(config)
+	current, err := os.ReadFile(path)
+	if err == nil {
+		entries, err := ListHistory()
+		if err != nil {
+			return err
+		}
+		var last []byte
+		if len(entries) > 0 {
+			last, _ = os.ReadFile(entries[0].Path)
+		}
+		if !bytes.Equal(current, last) {
+			if err := recordHistory(current, externalHistoryList); err != nil {
+				return err
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := WriteFileAtomic(path, data, 0644); err != nil {
+		return err
+	}
+	return recordHistory(data, list)
+}
+
+// n as shown by history ls, 0 is the newest
+func RestoreHistory(n int, config Config) (*HistoryEntry, error) {
+	entries, err := ListHistory()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n >= len(entries) {
+		return nil, fmt.Errorf("no history entry %d, there are %d", n, len(entries))
+	}
+	entry := entries[n]
+	data, err := os.ReadFile(entry.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, WriteModsConfig(data, config, entry.List)
+}
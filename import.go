@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"strings"
+)
+
+// RimWorld's own saved mod list (.rml), which is also what RimPy exports
+type SavedModList struct {
+	XMLName xml.Name `xml:"savedModList"`
+	Meta    SaveMeta `xml:"meta"`
+	ModList struct {
+		IDs   []string `xml:"ids>li"`
+		Names []string `xml:"names>li"`
+	} `xml:"modList"`
+}
+
+// RimWorld appends this to the package ID of the steam copy when a local copy of the same mod is also installed
+const steamDuplicateSuffix = "_steam"
+
+// package ID as RimWorld writes it in ModsConfig.xml or a save, and whether it names the steam copy
+func NormalizeModID(raw string) (PackageID, bool) {
+	pid := strings.ToLower(strings.TrimSpace(raw))
+	base, steam := strings.CutSuffix(pid, steamDuplicateSuffix)
+	return PackageID(base), steam
+}
+
+// looks up each package ID among pool, keeping the order of pids. the first copy in pool wins,
+// except for "_steam" IDs which get the steam copy when pool has one.
+// when a package ID isn't installed, the steam ID at the same index (if any, and not 0) is tried instead
+func ResolvePackageIDs(pids []string, steamIDs []SteamID, pool []*Mod) ([]*Mod, []PackageID) {
+	modsByPid := map[PackageID]*Mod{}
+	steamByPid := map[PackageID]*Mod{}
+	for _, mod := range pool {
+		if _, ok := modsByPid[mod.PackageID]; !ok {
+			modsByPid[mod.PackageID] = mod
+		}
+		if _, ok := steamByPid[mod.PackageID]; !ok && mod.Source == ModSourceSteam {
+			steamByPid[mod.PackageID] = mod
+		}
+	}
+	var modsBySteamID map[SteamID]*Mod
+
+	found := []*Mod{}
+	missing := []PackageID{}
+	for i, raw := range pids {
+		pid, steam := NormalizeModID(raw)
+		if pid == "" {
+			continue
+		}
+		if mod, ok := steamByPid[pid]; ok && steam {
+			found = append(found, mod)
+			continue
+		}
+		if mod, ok := modsByPid[pid]; ok {
+			found = append(found, mod)
+			continue
+		}
+		if i < len(steamIDs) && steamIDs[i] != 0 {
+			if modsBySteamID == nil {
+				modsBySteamID = map[SteamID]*Mod{}
+				for _, mod := range pool {
+					if mod.Source == ModSourceSteam {
+						modsBySteamID[mod.GetPublishedAppID()] = mod
+					}
+				}
+			}
+			if mod, ok := modsBySteamID[steamIDs[i]]; ok {
+				found = append(found, mod)
+				continue
+			}
+		}
+		missing = append(missing, pid)
+	}
+	return found, missing
+}
+
+func ImportModsConfig(config Config) ([]*Mod, []PackageID, error) {
+	modConfig, err := GetModlist(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	found, missing := ResolvePackageIDs(modConfig.ActiveMods, nil, GetModPool(config))
+	return found, missing, nil
+}
+
+// RimSort exports lists in the same shape as ModsConfig.xml
+func ImportRimSort(path string, config Config) ([]*Mod, []PackageID, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var modConfig ModConfig
+	if err := xml.Unmarshal(data, &modConfig); err != nil {
+		return nil, nil, err
+	}
+	found, missing := ResolvePackageIDs(modConfig.ActiveMods, nil, GetModPool(config))
+	return found, missing, nil
+}
+
+func ImportRimPy(path string, config Config) ([]*Mod, []PackageID, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var list SavedModList
+	if err := xml.Unmarshal(data, &list); err != nil {
+		return nil, nil, err
+	}
+	pids := list.Meta.ModIDs
+	if len(pids) == 0 {
+		pids = list.ModList.IDs
+	}
+	found, missing := ResolvePackageIDs(pids, list.Meta.SteamIDs(), GetModPool(config))
+	return found, missing, nil
+}
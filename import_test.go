@@ -0,0 +1,21 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestResolvePackageIDsSteamSuffix(t *testing.T) {
+	local := &Mod{PackageID: "author.mod", Source: ModSourceLocal}
+	steam := &Mod{PackageID: "author.mod", Source: ModSourceSteam}
+	other := &Mod{PackageID: "other.mod", Source: ModSourceLocal}
+	pool := []*Mod{local, other, steam}
+
+	found, missing := ResolvePackageIDs([]string{"Author.Mod_steam", "author.mod", "other.mod_steam", "gone.mod_steam"}, nil, pool)
+	if want := []*Mod{steam, local, other}; !slices.Equal(found, want) {
+		t.Errorf("found %v, want %v", found, want)
+	}
+	if want := []PackageID{"gone.mod"}; !slices.Equal(missing, want) {
+		t.Errorf("missing %v, want %v", missing, want)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const defaultList = "list.tsv"
+
+var ErrListExists = errors.New("List already exists")
+
+func GetListsPath() string {
+	return filepath.Join(GetConfigPath(), "lists")
+}
+
+// a --list value is either a path to a tsv or the name of a list in GetListsPath
+func ResolveListPath(list string) string {
+	if list == "" {
+		return defaultList
+	}
+	if strings.ContainsRune(list, filepath.Separator) || strings.HasSuffix(list, ".tsv") {
+		return list
+	}
+	return filepath.Join(GetListsPath(), list+".tsv")
+}
+
+func ListNames() ([]string, error) {
+	entries, err := os.ReadDir(GetListsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".tsv" {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(entry.Name(), ".tsv"))
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func namedListPath(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid list name %q", name)
+	}
+	return filepath.Join(GetListsPath(), name+".tsv"), nil
+}
+
+func NewList(name string, data []byte) error {
+	path, err := namedListPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(GetListsPath(), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%w: %s", ErrListExists, name)
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(data)
+	return err
+}
+
+func CopyList(src string, dst string) error {
+	data, err := os.ReadFile(ResolveListPath(src))
+	if err != nil {
+		return err
+	}
+	return NewList(dst, data)
+}
+
+func RemoveList(name string) error {
+	path, err := namedListPath(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// writes mods as a tsv list in the same layout as GetTSV, refusing to replace an existing file unless overwrite is set
+func WriteList(path string, mods []*Mod, overwrite bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%w: %s", ErrListExists, path)
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(GetTSV(mods))
+	return err
+}
+
+func AppendToList(path string, mods []*Mod) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		if _, err := file.WriteString("\n"); err != nil {
+			return err
+		}
+	}
+	_, err = file.WriteString(GetTSV(mods))
+	return err
+}
+
+// strict dependents are mods left without any provider once removed is gone, covered ones still have an alternative
+func dependentsOf(mods []*Mod, removed map[PackageID]bool) (strict []*Mod, covered []*Mod) {
+	remaining := map[PackageID]bool{}
+	for _, mod := range mods {
+		if !removed[mod.PackageID] {
+			remaining[mod.PackageID] = true
+		}
+	}
+	for _, mod := range mods {
+		if removed[mod.PackageID] {
+			continue
+		}
+		for _, group := range mod.Deps {
+			if !slices.ContainsFunc(group, func(pid PackageID) bool { return removed[pid] }) {
+				continue
+			}
+			if slices.ContainsFunc(group, func(pid PackageID) bool { return remaining[pid] }) {
+				if !slices.Contains(covered, mod) {
+					covered = append(covered, mod)
+				}
+				continue
+			}
+			if !slices.Contains(strict, mod) {
+				strict = append(strict, mod)
+			}
+		}
+	}
+	return strict, covered
+}
+
+// drops pid from the list file, along with everything that would be left without a dependency if cascade is set
+func RemoveFromList(path string, pid PackageID, cascade bool, config Config) ([]PackageID, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = '\t'
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	rowPids := make([]PackageID, len(rows))
+	mods := []*Mod{}
+	for i, row := range rows {
+		if len(row) < 4 || row[3] == "" {
+			continue
+		}
+		mod, err := ParseMod(row[3], config)
+		if err != nil {
+			continue
+		}
+		rowPids[i] = mod.PackageID
+		mods = append(mods, mod)
+	}
+	if !slices.Contains(rowPids, pid) {
+		return nil, fmt.Errorf("%s is not in %s", pid, path)
+	}
+
+	removed := map[PackageID]bool{pid: true}
+	order := []PackageID{pid}
+	for {
+		strict, covered := dependentsOf(mods, removed)
+		if len(strict) == 0 {
+			for _, mod := range covered {
+				fmt.Printf("%s still has an alternative provider\n", mod)
+			}
+			break
+		}
+		if !cascade {
+			for _, mod := range strict {
+				fmt.Printf("%s strictly depends on it\n", mod)
+			}
+			return nil, fmt.Errorf("refusing to remove %s, %d mods depend on it (use --cascade to remove them too)", pid, len(strict))
+		}
+		for _, mod := range strict {
+			removed[mod.PackageID] = true
+			order = append(order, mod.PackageID)
+		}
+	}
+
+	kept := [][]string{}
+	for i, row := range rows {
+		if !removed[rowPids[i]] {
+			kept = append(kept, row)
+		}
+	}
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Comma = '\t'
+	if err := writer.WriteAll(kept); err != nil {
+		return nil, err
+	}
+	return order, os.WriteFile(path, buf.Bytes(), 0644)
+}
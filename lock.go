@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+type GitLock struct {
+	PackageID PackageID `toml:"package-id"`
+	URL       string    `toml:"url"`
+	Commit    string    `toml:"commit"`
+}
+
+type SteamLock struct {
+	PackageID PackageID `toml:"package-id"`
+	SteamID   SteamID   `toml:"steam-id"`
+	// workshop update time of the installed copy from Steam's manifest,
+	// or the newest file in the mod folder when the manifest doesn't list it
+	TimeUpdated int64 `toml:"time-updated"`
+}
+
+type Lockfile struct {
+	Git   []GitLock   `toml:"git"`
+	Steam []SteamLock `toml:"steam"`
+}
+
+// lockfiles sit next to their list, e.g. list.tsv.lock
+func LockPath(listPath string) string {
+	return listPath + ".lock"
+}
+
+// records what is installed locally, nothing is fetched from the workshop
+func BuildLockfile(mods []*Mod, config Config) (*Lockfile, error) {
+	lock := &Lockfile{Git: []GitLock{}, Steam: []SteamLock{}}
+	installed, err := ReadWorkshopManifest(config)
+	if err != nil && slices.ContainsFunc(mods, func(mod *Mod) bool { return mod.Source == ModSourceSteam }) {
+		fmt.Printf("Couldn't read Steam's workshop manifest, using file times instead: %v\n", err)
+	}
+	for _, mod := range mods {
+		switch mod.Source {
+		case ModSourceGit:
+			url, err := runGit(mod.Path, "remote", "get-url", "origin")
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", mod.PackageID, err)
+			}
+			commit, err := GitHead(mod.Path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", mod.PackageID, err)
+			}
+			lock.Git = append(lock.Git, GitLock{PackageID: mod.PackageID, URL: url, Commit: commit})
+		case ModSourceSteam:
+			entry := SteamLock{PackageID: mod.PackageID, SteamID: mod.GetPublishedAppID()}
+			if item, ok := installed[entry.SteamID]; ok && item.TimeUpdated != 0 {
+				entry.TimeUpdated = item.TimeUpdated
+			} else {
+				entry.TimeUpdated, err = newestModTime(mod.Path)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", mod.PackageID, err)
+				}
+			}
+			lock.Steam = append(lock.Steam, entry)
+		}
+	}
+	return lock, nil
+}
+
+func WriteLockfile(path string, lock *Lockfile) error {
+	data, err := toml.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func ReadLockfile(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lock Lockfile
+	if err := toml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &lock, nil
+}
+
+// differences between what was locked and current, the lockfile of what is installed now
+func (lock *Lockfile) Drift(current *Lockfile) []string {
+	drift := []string{}
+
+	currentGit := map[PackageID]GitLock{}
+	for _, entry := range current.Git {
+		currentGit[entry.PackageID] = entry
+	}
+	lockedGit := map[PackageID]bool{}
+	for _, locked := range lock.Git {
+		lockedGit[locked.PackageID] = true
+		now, ok := currentGit[locked.PackageID]
+		switch {
+		case !ok:
+			drift = append(drift, fmt.Sprintf("%s is locked but not an installed git mod in the list", locked.PackageID))
+		case now.URL != locked.URL:
+			drift = append(drift, fmt.Sprintf("%s remote changed from %s to %s", locked.PackageID, locked.URL, now.URL))
+		case now.Commit != locked.Commit:
+			drift = append(drift, fmt.Sprintf("%s is at %.8s, locked at %.8s", locked.PackageID, now.Commit, locked.Commit))
+		}
+	}
+	for _, entry := range current.Git {
+		if !lockedGit[entry.PackageID] {
+			drift = append(drift, fmt.Sprintf("%s is not in the lockfile", entry.PackageID))
+		}
+	}
+
+	currentSteam := map[PackageID]SteamLock{}
+	for _, entry := range current.Steam {
+		currentSteam[entry.PackageID] = entry
+	}
+	lockedSteam := map[PackageID]bool{}
+	for _, locked := range lock.Steam {
+		lockedSteam[locked.PackageID] = true
+		now, ok := currentSteam[locked.PackageID]
+		switch {
+		case !ok:
+			drift = append(drift, fmt.Sprintf("%s is locked but not an installed steam mod in the list", locked.PackageID))
+		case now.SteamID != locked.SteamID:
+			drift = append(drift, fmt.Sprintf("%s is workshop item %d, locked as %d", locked.PackageID, now.SteamID, locked.SteamID))
+		case now.TimeUpdated != locked.TimeUpdated:
+			drift = append(drift, fmt.Sprintf(
+				"%s installed copy is from %s, locked at %s", locked.PackageID,
+				time.Unix(now.TimeUpdated, 0).Format(time.DateTime), time.Unix(locked.TimeUpdated, 0).Format(time.DateTime),
+			))
+		}
+	}
+	for _, entry := range current.Steam {
+		if !lockedSteam[entry.PackageID] {
+			drift = append(drift, fmt.Sprintf("%s is not in the lockfile", entry.PackageID))
+		}
+	}
+	return drift
+}
@@ -1,24 +1,29 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/urfave/cli/v3"
 	"log"
 	"os"
 	"path/filepath"
-	"slices"
 	"strconv"
 	"strings"
 )
 
-func CmdVanilla(context.Context, *cli.Command) error {
+func CmdVanilla(ctx context.Context, cmd *cli.Command) error {
 	config := LoadConfig()
 	mods, err := GetRimworldExpansions(config)
 	if err != nil {
 		return err
 	}
-	return LoadModlist(mods, config)
+	mods, err = WithoutDLCs(mods, cmd.StringSlice("without-dlc"), config)
+	if err != nil {
+		return err
+	}
+	return LoadModlist(mods, config, LoadOptions{ListName: "vanilla"})
 }
 func CmdTsv(context.Context, *cli.Command) error {
 	config := LoadConfig()
@@ -26,58 +31,199 @@ func CmdTsv(context.Context, *cli.Command) error {
 	fmt.Println(GetTSV(mods))
 	return nil
 }
-func CmdLoad(context.Context, *cli.Command) error {
+
+// pulls missing dependencies of mods into the list file, downloading them from the workshop
+// when they aren't installed. returns the complete list
+func resolveList(cmd *cli.Command, config Config, filename string, mods []*Mod) ([]*Mod, error) {
+	choose := PreferFirstProvider
+	if cmd.Bool("interactive") {
+		choose = PromptProvider
+	}
+	dryRun := cmd.Bool("dry-run")
+
+	all := mods
+	added := []*Mod{}
+	attempted := map[SteamID]bool{}
+	var installErr error
+	for {
+		newMods, unmet := ResolveDeps(all, GetAllMods(config), choose)
+		added = append(added, newMods...)
+		all = append(all, newMods...)
+
+		toInstall := []SteamID{}
+		for _, dep := range unmet {
+			id, ok := dep.WorkshopID()
+			if !ok || attempted[id] {
+				fmt.Printf("No installed mod satisfies %s\n", dep)
+				continue
+			}
+			attempted[id] = true
+			toInstall = append(toInstall, id)
+			if dryRun {
+				fmt.Printf("Would download %d for %s\n", id, dep)
+			}
+		}
+		if len(toInstall) == 0 || dryRun {
+			break
+		}
+		// items that didn't download stay unmet, everything else is still resolved and written
+		err := SteamCMDInstall(config, toInstall)
+		var missing *SteamCMDMissingError
+		if errors.As(err, &missing) {
+			fmt.Printf("Failed to download %v, leaving those dependencies unmet\n", missing.IDs)
+		} else if err != nil {
+			installErr = err
+			break
+		}
+	}
+
+	if dryRun {
+		fmt.Printf("Would add %d mods to %s\n", len(added), filename)
+		return all, nil
+	}
+	if len(added) > 0 {
+		if err := AppendToList(filename, added); err != nil {
+			return nil, err
+		}
+		fmt.Printf("Added %d mods to %s\n", len(added), filename)
+	}
+	return all, installErr
+}
+func CmdResolve(ctx context.Context, cmd *cli.Command) error {
+	config := LoadConfig()
+	filename := ResolveListPath(cmd.String("list"))
+
+	mods, err := GetModsFromPath(filename, config)
+	if err != nil {
+		return err
+	}
+	_, err = resolveList(cmd, config, filename, mods)
+	return err
+}
+func CmdLoad(ctx context.Context, cmd *cli.Command) error {
 	config := LoadConfig()
-	filename := "list.tsv"
+	filename := ResolveListPath(cmd.String("list"))
 
 	mods, err := GetModsFromPath(filename, config)
 	if err != nil {
 		return err
 	}
+	if cmd.Bool("resolve") {
+		mods, err = resolveList(cmd, config, filename, mods)
+		if err != nil {
+			return err
+		}
+	}
+	mods, err = WithoutDLCs(mods, cmd.StringSlice("without-dlc"), config)
+	if err != nil {
+		return err
+	}
 
-	return LoadModlist(mods, config)
+	order, err := SortOrder(cmd.String("keep-order"), mods, config)
+	if err != nil {
+		return err
+	}
+
+	return LoadModlist(mods, config, LoadOptions{
+		AllowIncompatible: cmd.Bool("allow-incompatible"),
+		Order:             order,
+		DryRun:            cmd.Bool("dry-run"),
+		ListName:          strings.TrimSuffix(filepath.Base(filename), ".tsv"),
+	})
 }
 
-func CmdGetDeps(ctx context.Context, cmd *cli.Command) error {
+func depTree(cmd *cli.Command) (*DepTree, PackageID, error) {
 	config := LoadConfig()
-	filename := "list.tsv"
+	filename := ResolveListPath(cmd.String("list"))
 	arg := cmd.Args().Slice()
 	if len(arg) < 1 {
-		return fmt.Errorf("GetDeps must be called with a packageid")
+		return nil, "", fmt.Errorf("deps must be called with a packageid")
 	}
 	pid := PackageID(strings.ToLower(arg[0]))
 
 	mods, err := GetModsFromPath(filename, config)
+	if err != nil {
+		return nil, "", err
+	}
+	universe := mods
+	if cmd.Bool("all") {
+		universe = GetAllMods(config)
+	}
+	return NewDepTree(universe, mods, cmd.Bool("all")), pid, nil
+}
+func CmdDepsOf(ctx context.Context, cmd *cli.Command) error {
+	tree, pid, err := depTree(cmd)
+	if err != nil {
+		return err
+	}
+	fmt.Println(tree.Of(pid))
+	return nil
+}
+func CmdDepsOn(ctx context.Context, cmd *cli.Command) error {
+	tree, pid, err := depTree(cmd)
+	if err != nil {
+		return err
+	}
+	fmt.Println(tree.On(pid))
+	return nil
+}
+func CmdWhy(ctx context.Context, cmd *cli.Command) error {
+	config := LoadConfig()
+	filename := ResolveListPath(cmd.String("list"))
+	args := cmd.Args().Slice()
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("why must be called with one or two packageids")
+	}
+
+	mods, err := GetModsFromPath(filename, config)
+	if err != nil {
+		return err
+	}
+	rules, err := LoadRules()
 	if err != nil {
 		return err
 	}
-	LinkMods(mods)
+	LinkMods(mods, rules)
+
+	modsByPid := map[PackageID]*Mod{}
 	for _, mod := range mods {
-		depGroups := mod.Deps
-		for _, group := range depGroups {
-			if !slices.Contains(group, pid) {
-				continue
-			}
-			if len(group) == 1 {
-				fmt.Printf("%s strict dependency\n", mod)
-				continue
-			}
-			fmt.Printf("%s depends but has alternative: %v\n", mod, group)
+		modsByPid[mod.PackageID] = mod
+	}
+	targets := []*Mod{}
+	for _, arg := range args {
+		mod, ok := modsByPid[PackageID(strings.ToLower(arg))]
+		if !ok {
+			return fmt.Errorf("%s is not in %s", arg, filename)
 		}
+		targets = append(targets, mod)
+	}
+
+	if len(targets) == 1 {
+		fmt.Println(ExplainMod(targets[0], mods, rules))
+	} else {
+		fmt.Println(ExplainOrder(targets[0], targets[1], mods, rules))
 	}
 	return nil
 }
-func CmdMarkdown(context.Context, *cli.Command) error {
+func CmdMarkdown(ctx context.Context, cmd *cli.Command) error {
 	config := LoadConfig()
-	filename := "list.tsv"
+	filename := ResolveListPath(cmd.String("list"))
 
 	mods, err := GetModsFromPath(filename, config)
 	if err != nil {
 		return err
 	}
-	LinkMods(mods)
+	rules, err := LoadRules()
+	if err != nil {
+		return err
+	}
+	LinkMods(mods, rules)
 	AddSteamInfo(mods, false)
-	sortedMods, err := SortMods(mods)
+	order, err := SortOrder(cmd.String("keep-order"), mods, config)
+	if err != nil {
+		return err
+	}
+	sortedMods, err := SortMods(mods, rules, order)
 	if err != nil {
 		return err
 	}
@@ -85,6 +231,336 @@ func CmdMarkdown(context.Context, *cli.Command) error {
 	fmt.Println(ExportMarkdown(sortedMods, config))
 	return nil
 }
+func CmdExportGraph(ctx context.Context, cmd *cli.Command) error {
+	config := LoadConfig()
+	filename := ResolveListPath(cmd.String("list"))
+
+	mods, err := GetModsFromPath(filename, config)
+	if err != nil {
+		return err
+	}
+	rules, err := LoadRules()
+	if err != nil {
+		return err
+	}
+	LinkMods(mods, rules)
+
+	graph := BuildGraph(mods, cmd.Bool("core"))
+	if around := cmd.String("around"); around != "" {
+		pid := PackageID(strings.ToLower(around))
+		if _, ok := graph[pid]; !ok {
+			return fmt.Errorf("%s is not in the graph", around)
+		}
+		graph = graph.Around(pid, cmd.Int("depth"))
+	}
+
+	switch cmd.String("format") {
+	case "dot":
+		fmt.Println(graph.DOT())
+	case "json":
+		out, err := graph.JSON()
+		if err != nil {
+			return err
+		}
+		fmt.Println(out)
+	default:
+		return fmt.Errorf("unknown graph format %q, expected dot or json", cmd.String("format"))
+	}
+	return nil
+}
+func CmdListLs(context.Context, *cli.Command) error {
+	names, err := ListNames()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
+func CmdListNew(ctx context.Context, cmd *cli.Command) error {
+	args := cmd.Args().Slice()
+	if len(args) != 1 {
+		return fmt.Errorf("list new must be called with a name")
+	}
+	return NewList(args[0], []byte{})
+}
+func CmdListCopy(ctx context.Context, cmd *cli.Command) error {
+	args := cmd.Args().Slice()
+	if len(args) != 2 {
+		return fmt.Errorf("list copy must be called with a source list and a new name")
+	}
+	return CopyList(args[0], args[1])
+}
+func CmdListRm(ctx context.Context, cmd *cli.Command) error {
+	args := cmd.Args().Slice()
+	if len(args) != 1 {
+		return fmt.Errorf("list rm must be called with a name")
+	}
+	return RemoveList(args[0])
+}
+func writeImported(cmd *cli.Command, mods []*Mod, missing []PackageID) error {
+	filename := ResolveListPath(cmd.String("list"))
+	for _, pid := range missing {
+		fmt.Printf("No installed mod for %s\n", pid)
+	}
+	if err := WriteList(filename, mods, cmd.Bool("force")); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d mods to %s\n", len(mods), filename)
+	return nil
+}
+func CmdImportModsConfig(ctx context.Context, cmd *cli.Command) error {
+	config := LoadConfig()
+	mods, missing, err := ImportModsConfig(config)
+	if err != nil {
+		return err
+	}
+	return writeImported(cmd, mods, missing)
+}
+func CmdImportRimSort(ctx context.Context, cmd *cli.Command) error {
+	config := LoadConfig()
+	args := cmd.Args().Slice()
+	if len(args) != 1 {
+		return fmt.Errorf("import rimsort must be called with a file")
+	}
+	mods, missing, err := ImportRimSort(args[0], config)
+	if err != nil {
+		return err
+	}
+	return writeImported(cmd, mods, missing)
+}
+func CmdImportRimPy(ctx context.Context, cmd *cli.Command) error {
+	config := LoadConfig()
+	args := cmd.Args().Slice()
+	if len(args) != 1 {
+		return fmt.Errorf("import rimpy must be called with a file")
+	}
+	mods, missing, err := ImportRimPy(args[0], config)
+	if err != nil {
+		return err
+	}
+	return writeImported(cmd, mods, missing)
+}
+func CmdImportSave(ctx context.Context, cmd *cli.Command) error {
+	config := LoadConfig()
+	args := cmd.Args().Slice()
+	if len(args) != 1 {
+		return fmt.Errorf("import save must be called with a save file")
+	}
+
+	meta, err := ReadSaveMeta(args[0])
+	if err != nil {
+		return err
+	}
+	mods, missing := ResolvePackageIDs(meta.ModIDs, meta.SteamIDs(), GetModPool(config))
+
+	toInstall := meta.MissingSteamIDs(missing, config)
+	if len(toInstall) > 0 {
+		fmt.Printf("%d mods from the save can be downloaded from Steam: %v\n", len(toInstall), toInstall)
+		if cmd.Bool("yes") || Confirm("Install them with SteamCMD?") {
+			if err := SteamCMDInstall(config, toInstall); err != nil {
+				return err
+			}
+			mods, missing = ResolvePackageIDs(meta.ModIDs, meta.SteamIDs(), GetModPool(config))
+		}
+	}
+
+	return writeImported(cmd, mods, missing)
+}
+func CmdExportRimSort(ctx context.Context, cmd *cli.Command) error {
+	return exportSorted(cmd, ExportRimSort)
+}
+func CmdExportRimPy(ctx context.Context, cmd *cli.Command) error {
+	return exportSorted(cmd, ExportRimPy)
+}
+func exportSorted(cmd *cli.Command, export func([]*Mod, Config) (string, error)) error {
+	config := LoadConfig()
+	filename := ResolveListPath(cmd.String("list"))
+
+	mods, err := GetModsFromPath(filename, config)
+	if err != nil {
+		return err
+	}
+	rules, err := LoadRules()
+	if err != nil {
+		return err
+	}
+	LinkMods(mods, rules)
+	order, err := SortOrder(cmd.String("keep-order"), mods, config)
+	if err != nil {
+		return err
+	}
+	sortedMods, err := SortMods(mods, rules, order)
+	if err != nil {
+		return err
+	}
+
+	out, err := export(sortedMods, config)
+	if err != nil {
+		return err
+	}
+	fmt.Println(out)
+	return nil
+}
+func CmdSaveCheck(ctx context.Context, cmd *cli.Command) error {
+	config := LoadConfig()
+	filename := ResolveListPath(cmd.String("list"))
+	args := cmd.Args().Slice()
+	if len(args) != 1 {
+		return fmt.Errorf("savecheck must be called with a save file")
+	}
+
+	meta, err := ReadSaveMeta(args[0])
+	if err != nil {
+		return err
+	}
+	mods, err := GetModsFromPath(filename, config)
+	if err != nil {
+		return err
+	}
+	rules, err := LoadRules()
+	if err != nil {
+		return err
+	}
+	LinkMods(mods, rules)
+	order, err := SortOrder(cmd.String("keep-order"), mods, config)
+	if err != nil {
+		return err
+	}
+	sortedMods, err := SortMods(mods, rules, order)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(CompareSave(meta, sortedMods, GetModPool(config)))
+	return nil
+}
+func CmdListRemove(ctx context.Context, cmd *cli.Command) error {
+	config := LoadConfig()
+	filename := ResolveListPath(cmd.String("list"))
+	args := cmd.Args().Slice()
+	if len(args) != 1 {
+		return fmt.Errorf("list remove must be called with a packageid")
+	}
+
+	removed, err := RemoveFromList(filename, PackageID(strings.ToLower(args[0])), cmd.Bool("cascade"), config)
+	if err != nil {
+		return err
+	}
+	for _, pid := range removed {
+		fmt.Printf("Removed %s\n", pid)
+	}
+	return nil
+}
+func CmdGitClone(ctx context.Context, cmd *cli.Command) error {
+	config := LoadConfig()
+	args := cmd.Args().Slice()
+	if len(args) != 1 {
+		return fmt.Errorf("git clone must be called with a url")
+	}
+	path, err := GitClone(config, args[0])
+	if err != nil {
+		return err
+	}
+	mod, err := ParseMod(path, config)
+	if err != nil {
+		fmt.Printf("Cloned to %s, but it has no valid About.xml\n", path)
+		return err
+	}
+	fmt.Printf("Cloned %s\n", mod)
+	return nil
+}
+func CmdGitPull(ctx context.Context, cmd *cli.Command) error {
+	config := LoadConfig()
+	pids := []PackageID{}
+	for _, arg := range cmd.Args().Slice() {
+		pids = append(pids, PackageID(strings.ToLower(arg)))
+	}
+
+	updates, unmatched := GitPull(config, pids)
+	failed := len(unmatched)
+	for _, pid := range unmatched {
+		fmt.Printf("%s is not an installed git mod\n", pid)
+	}
+	for _, update := range updates {
+		fmt.Println(update)
+		if update.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d git mods failed to update", failed)
+	}
+	return nil
+}
+func currentLock(filename string) (*Lockfile, error) {
+	config := LoadConfig()
+	mods, err := GetModsFromPath(filename, config)
+	if err != nil {
+		return nil, err
+	}
+	return BuildLockfile(mods, config)
+}
+func CmdLock(ctx context.Context, cmd *cli.Command) error {
+	filename := ResolveListPath(cmd.String("list"))
+	lock, err := currentLock(filename)
+	if err != nil {
+		return err
+	}
+	if err := WriteLockfile(LockPath(filename), lock); err != nil {
+		return err
+	}
+	fmt.Printf("Locked %d git and %d steam mods in %s\n", len(lock.Git), len(lock.Steam), LockPath(filename))
+	return nil
+}
+func CmdLockVerify(ctx context.Context, cmd *cli.Command) error {
+	filename := ResolveListPath(cmd.String("list"))
+	locked, err := ReadLockfile(LockPath(filename))
+	if err != nil {
+		return err
+	}
+	current, err := currentLock(filename)
+	if err != nil {
+		return err
+	}
+	drift := locked.Drift(current)
+	for _, line := range drift {
+		fmt.Println(line)
+	}
+	if len(drift) > 0 {
+		return fmt.Errorf("%d mods drifted from %s", len(drift), LockPath(filename))
+	}
+	fmt.Println("Everything matches the lockfile")
+	return nil
+}
+func CmdHistoryLs(context.Context, *cli.Command) error {
+	entries, err := ListHistory()
+	if err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		fmt.Printf("%d\t%s\t%s\n", i, entry.Time.Format("2006-01-02 15:04:05"), entry.List)
+	}
+	return nil
+}
+func CmdHistoryRestore(ctx context.Context, cmd *cli.Command) error {
+	config := LoadConfig()
+	args := cmd.Args().Slice()
+	if len(args) != 1 {
+		return fmt.Errorf("history restore must be called with an entry number from history ls")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+	entry, err := RestoreHistory(n, config)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored ModsConfig.xml from %s (%s)\n", entry.Time.Format("2006-01-02 15:04:05"), entry.List)
+	return nil
+}
 func CmdToddsClean(context.Context, *cli.Command) error {
 	config := LoadConfig()
 	return ToddsClean(config)
@@ -93,15 +569,19 @@ func CmdToddsEncode(context.Context, *cli.Command) error {
 	config := LoadConfig()
 	return ToddsEncode(config)
 }
-func CmdSteam(context.Context, *cli.Command) error {
+func CmdSteam(ctx context.Context, cmd *cli.Command) error {
 	config := LoadConfig()
-	filename := "list.tsv"
+	filename := ResolveListPath(cmd.String("list"))
 
 	mods, err := GetModsFromPath(filename, config)
 	if err != nil {
 		return err
 	}
-	LinkMods(mods)
+	rules, err := LoadRules()
+	if err != nil {
+		return err
+	}
+	LinkMods(mods, rules)
 	AddSteamInfo(mods, false)
 	return nil
 }
@@ -145,12 +625,42 @@ func CmdUpdate(context.Context, *cli.Command) error {
 	return nil
 }
 
+var stdin = bufio.NewReader(os.Stdin)
+
+func Prompt(prompt string) string {
+	fmt.Printf("%s ", prompt)
+	answer, _ := stdin.ReadString('\n')
+	return strings.TrimSpace(answer)
+}
+
+func Confirm(prompt string) bool {
+	answer := strings.ToLower(Prompt(prompt + " [y/N]"))
+	return answer == "y" || answer == "yes"
+}
+
+var keepOrderFlag = &cli.StringFlag{
+	Name:  "keep-order",
+	Value: "alpha",
+	Usage: "break sorting ties by alpha, list (row order of the list) or modsconfig (current active order)",
+}
+
+var withoutDLCFlag = &cli.StringSliceFlag{
+	Name:  "without-dlc",
+	Usage: "leave out a DLC, by package ID or short name like anomaly (can be repeated)",
+}
+
+var interactiveFlag = &cli.BoolFlag{
+	Name:  "interactive",
+	Usage: "ask which mod to use when a dependency has several installed alternatives",
+}
+
 func main() {
 	commands := []*cli.Command{
 		{
 			Name:   "vanilla",
-			Usage:  "Load vanilla Rimworld with all expansions",
+			Usage:  "Load vanilla Rimworld with all expansions, or all but --without-dlc",
 			Action: CmdVanilla,
+			Flags:  []cli.Flag{withoutDLCFlag},
 		},
 		{
 			Name:   "check",
@@ -164,10 +674,39 @@ func main() {
 			Name:   "load",
 			Usage:  "output mods in TSV for use elswhere",
 			Action: CmdLoad,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "allow-incompatible",
+					Usage: "write ModsConfig.xml even if the list contains incompatible mods",
+				},
+				keepOrderFlag,
+				&cli.BoolFlag{
+					Name:  "resolve",
+					Usage: "add mods that satisfy missing dependencies to the list first, downloading them from the workshop if needed",
+				},
+				interactiveFlag,
+				withoutDLCFlag,
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "only print what would change, without touching the list, Mods or ModsConfig.xml",
+				},
+			},
+		}, {
+			Name:   "resolve",
+			Usage:  "add mods that satisfy missing dependencies to the list, downloading them from the workshop if needed",
+			Action: CmdResolve,
+			Flags: []cli.Flag{
+				interactiveFlag,
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "only print what would be added and downloaded",
+				},
+			},
 		}, {
 			Name:   "markdown",
 			Usage:  "markdown export",
 			Action: CmdMarkdown,
+			Flags:  []cli.Flag{keepOrderFlag},
 		}, {
 			Name:  "dds",
 			Usage: "tools for DDS encoding and cleaning",
@@ -182,14 +721,198 @@ func main() {
 					Action: CmdToddsEncode,
 				},
 			},
+		}, {
+			Name:  "export",
+			Usage: "export the list in other formats",
+			Commands: []*cli.Command{
+				{
+					Name:   "graph",
+					Usage:  "export the load order and dependency graph",
+					Action: CmdExportGraph,
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "format",
+							Value: "dot",
+							Usage: "dot or json",
+						},
+						&cli.StringFlag{
+							Name:  "around",
+							Usage: "only export mods near this packageid",
+						},
+						&cli.IntFlag{
+							Name:  "depth",
+							Value: 1,
+							Usage: "how many edges away from --around to include",
+						},
+						&cli.BoolFlag{
+							Name:  "core",
+							Usage: "include the implicit edges from every mod to the Ludeon mods",
+						},
+					},
+				}, {
+					Name:   "rimsort",
+					Usage:  "export the sorted list in RimSort's format",
+					Action: CmdExportRimSort,
+					Flags:  []cli.Flag{keepOrderFlag},
+				}, {
+					Name:   "rimpy",
+					Usage:  "export the sorted list as a RimPy or RimWorld (.rml) mod list",
+					Action: CmdExportRimPy,
+					Flags:  []cli.Flag{keepOrderFlag},
+				},
+			},
+		}, {
+			Name:  "list",
+			Usage: "manage named lists stored in the config directory",
+			Commands: []*cli.Command{
+				{
+					Name:   "ls",
+					Usage:  "show stored lists",
+					Action: CmdListLs,
+				}, {
+					Name:      "new",
+					Usage:     "create an empty list",
+					ArgsUsage: "<name>",
+					Action:    CmdListNew,
+				}, {
+					Name:      "copy",
+					Usage:     "copy a list (by name or path) to a new stored list",
+					ArgsUsage: "<list> <name>",
+					Action:    CmdListCopy,
+				}, {
+					Name:      "rm",
+					Usage:     "delete a stored list",
+					ArgsUsage: "<name>",
+					Action:    CmdListRm,
+				}, {
+					Name:      "remove",
+					Usage:     "remove a mod from the list, checking nothing else in it needs the mod",
+					ArgsUsage: "<packageid>",
+					Action:    CmdListRemove,
+					Flags: []cli.Flag{
+						&cli.BoolFlag{
+							Name:  "cascade",
+							Usage: "also remove mods that would be left without a dependency",
+						},
+					},
+				},
+			},
+		}, {
+			Name:  "import",
+			Usage: "create a list from other formats",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "force",
+					Usage: "overwrite the list if it already exists",
+				},
+			},
+			Commands: []*cli.Command{
+				{
+					Name:   "modsconfig",
+					Usage:  "import the currently active mods from ModsConfig.xml",
+					Action: CmdImportModsConfig,
+				}, {
+					Name:      "save",
+					Usage:     "import the mods a save (.rws) was made with",
+					ArgsUsage: "<save.rws>",
+					Action:    CmdImportSave,
+					Flags: []cli.Flag{
+						&cli.BoolFlag{
+							Name:  "yes",
+							Usage: "install missing Steam mods without asking",
+						},
+					},
+				}, {
+					Name:      "rimsort",
+					Usage:     "import a list exported by RimSort",
+					ArgsUsage: "<file.xml>",
+					Action:    CmdImportRimSort,
+				}, {
+					Name:      "rimpy",
+					Usage:     "import a RimPy or RimWorld (.rml) mod list",
+					ArgsUsage: "<file.xml>",
+					Action:    CmdImportRimPy,
+				},
+			},
+		}, {
+			Name:      "savecheck",
+			Usage:     "compare a save's mods with the sorted list",
+			ArgsUsage: "<save.rws>",
+			Action:    CmdSaveCheck,
+			Flags:     []cli.Flag{keepOrderFlag},
+		}, {
+			Name:  "git",
+			Usage: "manage mods cloned with git into the local mod source",
+			Commands: []*cli.Command{
+				{
+					Name:      "clone",
+					Usage:     "clone a mod repository",
+					ArgsUsage: "<url>",
+					Action:    CmdGitClone,
+				}, {
+					Name:      "pull",
+					Usage:     "update git mods, all of them unless PIDs are given",
+					ArgsUsage: "[packageid...]",
+					Action:    CmdGitPull,
+				},
+			},
+		}, {
+			Name:   "lock",
+			Usage:  "record the commit of every git mod and the installed version of every steam mod in the list",
+			Action: CmdLock,
+			Commands: []*cli.Command{
+				{
+					Name:   "verify",
+					Usage:  "report mods that no longer match the lockfile",
+					Action: CmdLockVerify,
+				},
+			},
+		}, {
+			Name:  "history",
+			Usage: "previous ModsConfig.xml files written by load and vanilla",
+			Commands: []*cli.Command{
+				{
+					Name:   "ls",
+					Usage:  "show the history, newest first",
+					Action: CmdHistoryLs,
+				}, {
+					Name:      "restore",
+					Usage:     "write a previous ModsConfig.xml back",
+					ArgsUsage: "<n>",
+					Action:    CmdHistoryRestore,
+				},
+			},
 		}, {
 			Name:   "install",
 			Usage:  "SteamCMD install",
 			Action: CmdInstall,
 		}, {
-			Name:   "getdeps",
-			Usage:  "Find dependents of a PID",
-			Action: CmdGetDeps,
+			Name:  "deps",
+			Usage: "show dependency trees",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "all",
+					Usage: "look through all installed mods, not just the list",
+				},
+			},
+			Commands: []*cli.Command{
+				{
+					Name:      "of",
+					Usage:     "everything a PID transitively depends on",
+					ArgsUsage: "<packageid>",
+					Action:    CmdDepsOf,
+				}, {
+					Name:      "on",
+					Usage:     "everything that transitively depends on a PID",
+					ArgsUsage: "<packageid>",
+					Action:    CmdDepsOn,
+				},
+			},
+		}, {
+			Name:      "why",
+			Usage:     "Explain why a mod loads where it does, or why one mod loads before another",
+			ArgsUsage: "<packageid> [packageid]",
+			Action:    CmdWhy,
 		}, {
 			Name:   "update",
 			Usage:  "update cache",
@@ -200,6 +923,13 @@ func main() {
 	cmd := &cli.Command{
 		Commands:              commands,
 		EnableShellCompletion: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "list",
+				Value: defaultList,
+				Usage: "list to use, either a path to a tsv or the name of a stored list",
+			},
+		},
 	}
 	if err := cmd.Run(context.Background(), os.Args); err != nil {
 		log.Fatal(err)
@@ -28,12 +28,40 @@ type About struct {
 	IncompatibleWith  []string        `xml:"incompatibleWith>li"`
 	Description       string          `xml:"description"`
 
-	// in my rather extensive modlist, these are used literally nowhere
-	LoadAfterByVersion        []string        `xml:"loadAfterByVersion>li"`
-	LoadBeforeByVersion       []string        `xml:"loadBeforeByVersion>li"`
-	ForceLoadAfterByVersion   []string        `xml:"forceLoadAfterByVersion>li"`
-	ModDependenciesByVersion  []ModDependency `xml:"modDependenciesByVersion>li"`
-	IncompatibleWithByVersion []string        `xml:"incompatibleWithByVersion>li"`
+	// rare, but some mods only declare their rules for a specific game version
+	LoadAfterByVersion        ByVersion[string]        `xml:"loadAfterByVersion"`
+	LoadBeforeByVersion       ByVersion[string]        `xml:"loadBeforeByVersion"`
+	ForceLoadAfterByVersion   ByVersion[string]        `xml:"forceLoadAfterByVersion"`
+	ModDependenciesByVersion  ByVersion[ModDependency] `xml:"modDependenciesByVersion"`
+	IncompatibleWithByVersion ByVersion[string]        `xml:"incompatibleWithByVersion"`
+}
+
+// keyed by major version without the v, e.g. <v1.6><li>..</li></v1.6> is stored under "1.6"
+type ByVersion[T any] map[string][]T
+
+func (byVersion *ByVersion[T]) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if *byVersion == nil {
+		*byVersion = ByVersion[T]{}
+	}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			var entry struct {
+				Items []T `xml:"li"`
+			}
+			if err := d.DecodeElement(&entry, &t); err != nil {
+				return err
+			}
+			version := strings.TrimPrefix(strings.ToLower(t.Name.Local), "v")
+			(*byVersion)[version] = append((*byVersion)[version], entry.Items...)
+		case xml.EndElement:
+			return nil
+		}
+	}
 }
 
 type ModDependency struct {
@@ -53,6 +81,20 @@ const (
 	ModSourceOfficial
 )
 
+func (source ModSource) String() string {
+	switch source {
+	case ModSourceLocal:
+		return "local"
+	case ModSourceSteam:
+		return "steam"
+	case ModSourceGit:
+		return "git"
+	case ModSourceOfficial:
+		return "official"
+	}
+	return "unknown"
+}
+
 type PackageID string
 
 type Mod struct {
@@ -60,7 +102,11 @@ type Mod struct {
 	PackageID PackageID
 	Source    ModSource
 	About     About
-	LoadAfter []*Mod
+	// major version the ByVersion rules are resolved against
+	GameVersion string
+	LoadAfter   []*Mod
+	// why each LoadAfter edge exists, filled in by LinkMods
+	LoadAfterOrigins map[*Mod][]EdgeOrigin
 	// inner list is "one of the following"
 	Deps      [][]PackageID
 	SteamInfo *SteamInfo
@@ -80,38 +126,96 @@ func (mod *Mod) String() string {
 	return fmt.Sprintf("%s @ %s", string(mod.PackageID), string(mod.Path))
 }
 
-func (mod *Mod) LoadAfterFull() []PackageID {
-	out := []PackageID{}
-	for _, pid := range mod.About.LoadAfter {
-		out = append(out, PackageID(strings.ToLower(pid)))
-	}
-	for _, pid := range mod.About.ForceLoadAfter {
-		out = append(out, PackageID(strings.ToLower(pid)))
+// where a LoadAfter edge came from, Before origins are declared by the mod that loads first
+type EdgeOrigin string
+
+const (
+	OriginLoadAfter               EdgeOrigin = "loadAfter"
+	OriginForceLoadAfter          EdgeOrigin = "forceLoadAfter"
+	OriginLoadAfterByVersion      EdgeOrigin = "loadAfterByVersion"
+	OriginForceLoadAfterByVersion EdgeOrigin = "forceLoadAfterByVersion"
+	OriginLoadBefore              EdgeOrigin = "loadBefore"
+	OriginForceLoadBefore         EdgeOrigin = "forceLoadBefore"
+	OriginLoadBeforeByVersion     EdgeOrigin = "loadBeforeByVersion"
+	OriginCore                    EdgeOrigin = "implicit Ludeon core edge"
+)
+
+func (origin EdgeOrigin) IsBefore() bool {
+	return origin == OriginLoadBefore || origin == OriginForceLoadBefore || origin == OriginLoadBeforeByVersion
+}
+
+func (origin EdgeOrigin) IsForced() bool {
+	return origin == OriginForceLoadAfter || origin == OriginForceLoadAfterByVersion || origin == OriginForceLoadBefore
+}
+
+type LoadEdge struct {
+	PackageID PackageID
+	Origin    EdgeOrigin
+}
+
+func (mod *Mod) LoadAfterEdges() []LoadEdge {
+	out := []LoadEdge{}
+	add := func(pids []string, origin EdgeOrigin) {
+		for _, pid := range pids {
+			out = append(out, LoadEdge{PackageID(strings.ToLower(pid)), origin})
+		}
 	}
-	// for _, pid := range mod.About.LoadAfterByVersion {
-	// 	out = append(out, PackageID(pid))
-	// }
+	add(mod.About.LoadAfter, OriginLoadAfter)
+	add(mod.About.ForceLoadAfter, OriginForceLoadAfter)
+	add(mod.About.LoadAfterByVersion[mod.GameVersion], OriginLoadAfterByVersion)
+	add(mod.About.ForceLoadAfterByVersion[mod.GameVersion], OriginForceLoadAfterByVersion)
 	if !slices.Contains(mod.LoadBeforeFull(), "ludeon.rimworld") && !(mod.Source == ModSourceOfficial) {
-		out = append(out, PackageID("ludeon.rimworld"))
-		out = append(out, PackageID("ludeon.rimworld.anomaly"))
-		out = append(out, PackageID("ludeon.rimworld.odyssey"))
-		out = append(out, PackageID("ludeon.rimworld.royalty"))
-		out = append(out, PackageID("ludeon.rimworld.ideology"))
-		out = append(out, PackageID("ludeon.rimworld.biotech"))
+		add([]string{
+			"ludeon.rimworld",
+			"ludeon.rimworld.anomaly",
+			"ludeon.rimworld.odyssey",
+			"ludeon.rimworld.royalty",
+			"ludeon.rimworld.ideology",
+			"ludeon.rimworld.biotech",
+		}, OriginCore)
+	}
+	return out
+}
+func (mod *Mod) LoadBeforeEdges() []LoadEdge {
+	out := []LoadEdge{}
+	add := func(pids []string, origin EdgeOrigin) {
+		for _, pid := range pids {
+			out = append(out, LoadEdge{PackageID(strings.ToLower(pid)), origin})
+		}
+	}
+	add(mod.About.LoadBefore, OriginLoadBefore)
+	add(mod.About.ForceLoadBefore, OriginForceLoadBefore)
+	add(mod.About.LoadBeforeByVersion[mod.GameVersion], OriginLoadBeforeByVersion)
+	return out
+}
+func (mod *Mod) LoadAfterFull() []PackageID {
+	out := []PackageID{}
+	for _, edge := range mod.LoadAfterEdges() {
+		out = append(out, edge.PackageID)
 	}
 	return out
 }
 func (mod *Mod) LoadBeforeFull() []PackageID {
 	out := []PackageID{}
-	for _, pid := range mod.About.LoadBefore {
+	for _, edge := range mod.LoadBeforeEdges() {
+		out = append(out, edge.PackageID)
+	}
+	return out
+}
+func (mod *Mod) ModDependenciesFull() []ModDependency {
+	out := []ModDependency{}
+	out = append(out, mod.About.ModDependencies...)
+	out = append(out, mod.About.ModDependenciesByVersion[mod.GameVersion]...)
+	return out
+}
+func (mod *Mod) IncompatibleWithFull() []PackageID {
+	out := []PackageID{}
+	for _, pid := range mod.About.IncompatibleWith {
 		out = append(out, PackageID(strings.ToLower(pid)))
 	}
-	for _, pid := range mod.About.ForceLoadBefore {
+	for _, pid := range mod.About.IncompatibleWithByVersion[mod.GameVersion] {
 		out = append(out, PackageID(strings.ToLower(pid)))
 	}
-	// for _, pid := range mod.About.LoadAfterByVersion {
-	// 	out = append(out, PackageID(pid))
-	// }
 	return out
 }
 func (mod *Mod) BestSupportedVersion() string {
@@ -140,7 +244,8 @@ func (mod *Mod) GetPublishedAppID() SteamID {
 	idStr := strings.TrimRight(string(content), "\r\n")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		fmt.Println("Invalid steam ID " + idStr)
+		// stderr, as exports write to stdout
+		fmt.Fprintf(os.Stderr, "Invalid steam ID %q in %s\n", idStr, mod.Path)
 		return 0
 	}
 	return SteamID(id)
@@ -183,7 +288,7 @@ func GetModSource(path string, config Config) ModSource {
 		return ModSourceOfficial
 	}
 	if dir == config.LocalModSrc {
-		_, err := os.Stat(filepath.Join(dir, ".git"))
+		_, err := os.Stat(filepath.Join(path, ".git"))
 		if err == nil {
 			return ModSourceGit
 		}
@@ -197,8 +302,16 @@ func ParseMod(path string, config Config) (*Mod, error) {
 		return nil, err
 	}
 
+	mod := &Mod{
+		Path:        path,
+		Source:      GetModSource(path, config),
+		PackageID:   PackageID(strings.ToLower(about.PackageID)),
+		About:       *about,
+		GameVersion: GetRimworldMajorVersion(config),
+	}
+
 	deps := [][]PackageID{}
-	for _, dep := range about.ModDependencies {
+	for _, dep := range mod.ModDependenciesFull() {
 		allowed_deps := []PackageID{}
 		allowed_deps = append(allowed_deps, PackageID(strings.ToLower(dep.PackageID)))
 		for _, alternate := range dep.AlternativePackageIds {
@@ -206,14 +319,9 @@ func ParseMod(path string, config Config) (*Mod, error) {
 		}
 		deps = append(deps, allowed_deps)
 	}
+	mod.Deps = deps
 
-	return &Mod{
-		Path:      path,
-		Source:    GetModSource(path, config),
-		PackageID: PackageID(strings.ToLower(about.PackageID)),
-		About:     *about,
-		Deps:      deps,
-	}, nil
+	return mod, nil
 }
 
 func GetAllModsPath(config Config) []string {
@@ -252,7 +360,8 @@ func GetAllModsPath(config Config) []string {
 	return paths
 }
 
-func GetAllMods(config Config) []*Mod {
+// every installed copy of every mod, duplicates included
+func GetInstalledMods(config Config) []*Mod {
 	mods := []*Mod{}
 	for _, modPath := range GetAllModsPath(config) {
 		mod, err := ParseMod(modPath, config)
@@ -263,3 +372,68 @@ func GetAllMods(config Config) []*Mod {
 	}
 	return mods
 }
+
+// one copy of each installed mod, picked by source preference
+func GetAllMods(config Config) []*Mod {
+	// unresolvable duplicates have already been reported, and the first copy is good enough for a pool
+	mods, _ := DedupeMods(GetInstalledMods(config), config)
+	return mods
+}
+
+// the preferred copy of each mod followed by every other copy, for ResolvePackageIDs
+func GetModPool(config Config) []*Mod {
+	installed := GetInstalledMods(config)
+	preferred, _ := DedupeMods(installed, config)
+	return append(preferred, installed...)
+}
+
+// keeps one mod per PackageID, picking by config.SourceRank. every duplicate is reported.
+// if copies tie on source the first is kept and ErrDuplicatePID is returned along with the deduped mods,
+// callers decide whether that guess is good enough (GetAllMods) or needs the user to choose (GetModsFromPath)
+func DedupeMods(mods []*Mod, config Config) ([]*Mod, error) {
+	byPid := map[PackageID][]*Mod{}
+	order := []PackageID{}
+	for _, mod := range mods {
+		if _, ok := byPid[mod.PackageID]; !ok {
+			order = append(order, mod.PackageID)
+		}
+		byPid[mod.PackageID] = append(byPid[mod.PackageID], mod)
+	}
+
+	out := make([]*Mod, 0, len(order))
+	ambiguous := []string{}
+	for _, pid := range order {
+		copies := byPid[pid]
+		best := copies[0]
+		for _, mod := range copies[1:] {
+			if config.SourceRank(mod.Source) < config.SourceRank(best.Source) {
+				best = mod
+			}
+		}
+		out = append(out, best)
+		if len(copies) == 1 {
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "Duplicate PackageID %s:\n", pid)
+		tied := 0
+		for _, mod := range copies {
+			marker := " "
+			if mod == best {
+				marker = "*"
+			}
+			if config.SourceRank(mod.Source) == config.SourceRank(best.Source) {
+				tied++
+			}
+			fmt.Fprintf(os.Stderr, " %s %s @ %s\n", marker, mod.Source, mod.Path)
+		}
+		if tied > 1 {
+			ambiguous = append(ambiguous, string(pid))
+		}
+	}
+
+	if len(ambiguous) > 0 {
+		return out, fmt.Errorf("%w: %s", ErrDuplicatePID, strings.Join(ambiguous, ", "))
+	}
+	return out, nil
+}
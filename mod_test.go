@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func parseForVersion(t *testing.T, version string) *Mod {
+	t.Helper()
+	targetDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(targetDir, "Version.txt"), []byte(version), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mod, err := ParseMod(filepath.Join("testdata", "byversion"), Config{TargetDir: targetDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mod
+}
+
+func TestParseModByVersion(t *testing.T) {
+	for _, tc := range []struct {
+		version      string
+		loadAfter    []PackageID
+		deps         [][]PackageID
+		incompatible []PackageID
+	}{
+		{
+			version:      "1.6.4633 rev1273",
+			loadAfter:    []PackageID{"always.after", "new.after", "other.new.after"},
+			deps:         [][]PackageID{{"brrainz.harmony"}, {"new.framework", "new.framework.fork"}},
+			incompatible: []PackageID{"always.incompatible", "new.incompatible"},
+		},
+		{
+			version:      "1.5.4409 rev1118",
+			loadAfter:    []PackageID{"always.after", "old.after"},
+			deps:         [][]PackageID{{"brrainz.harmony"}, {"old.framework"}},
+			incompatible: []PackageID{"always.incompatible", "old.incompatible"},
+		},
+		{
+			version:      "1.4.3901 rev1",
+			loadAfter:    []PackageID{"always.after"},
+			deps:         [][]PackageID{{"brrainz.harmony"}},
+			incompatible: []PackageID{"always.incompatible"},
+		},
+	} {
+		t.Run(tc.version, func(t *testing.T) {
+			mod := parseForVersion(t, tc.version)
+			if mod.PackageID != "test.byversion" {
+				t.Errorf("PackageID = %q", mod.PackageID)
+			}
+
+			loadAfter := []PackageID{}
+			for _, edge := range mod.LoadAfterEdges() {
+				if edge.Origin != OriginCore {
+					loadAfter = append(loadAfter, edge.PackageID)
+				}
+			}
+			if !slices.Equal(loadAfter, tc.loadAfter) {
+				t.Errorf("LoadAfterEdges = %v, want %v", loadAfter, tc.loadAfter)
+			}
+			if !slices.EqualFunc(mod.Deps, tc.deps, slices.Equal) {
+				t.Errorf("Deps = %v, want %v", mod.Deps, tc.deps)
+			}
+			if incompatible := mod.IncompatibleWithFull(); !slices.Equal(incompatible, tc.incompatible) {
+				t.Errorf("IncompatibleWithFull = %v, want %v", incompatible, tc.incompatible)
+			}
+		})
+	}
+}
+
+func TestByVersionKeys(t *testing.T) {
+	mod := parseForVersion(t, "1.6.4633 rev1273")
+	for name, byVersion := range map[string]ByVersion[string]{
+		"loadAfterByVersion":        mod.About.LoadAfterByVersion,
+		"incompatibleWithByVersion": mod.About.IncompatibleWithByVersion,
+	} {
+		keys := []string{}
+		for key := range byVersion {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		if !slices.Equal(keys, []string{"1.5", "1.6"}) {
+			t.Errorf("%s keys = %v, want [1.5 1.6]", name, keys)
+		}
+	}
+	if len(mod.About.ModDependenciesByVersion) != 2 {
+		t.Errorf("modDependenciesByVersion has %d versions, want 2", len(mod.About.ModDependenciesByVersion))
+	}
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"path/filepath"
 )
 
 type ModConfig struct {
@@ -16,16 +18,56 @@ type ModConfig struct {
 	ActiveMods      []string `xml:"activeMods>li"`
 }
 
-func SetModlist(mods []*Mod, config Config) error {
+func GetModlist(config Config) (*ModConfig, error) {
+	data, err := os.ReadFile(GetModsConfigPath(config))
+	if err != nil {
+		return nil, err
+	}
+	var modConfig ModConfig
+	if err := xml.Unmarshal(data, &modConfig); err != nil {
+		return nil, err
+	}
+	return &modConfig, nil
+}
+
+// tie-break order to keep when sorting: "alpha" (none), "list" (the order mods were given in)
+// or "modsconfig" (the currently active order)
+func SortOrder(mode string, mods []*Mod, config Config) ([]PackageID, error) {
+	switch mode {
+	case "", "alpha":
+		return nil, nil
+	case "list":
+		order := []PackageID{}
+		for _, mod := range mods {
+			order = append(order, mod.PackageID)
+		}
+		return order, nil
+	case "modsconfig":
+		modConfig, err := GetModlist(config)
+		if err != nil {
+			return nil, err
+		}
+		order := []PackageID{}
+		for _, pid := range modConfig.ActiveMods {
+			pid, _ := NormalizeModID(pid)
+			order = append(order, pid)
+		}
+		return order, nil
+	}
+	return nil, fmt.Errorf("unknown sort order %q, expected alpha, list or modsconfig", mode)
+}
+
+func BuildModConfig(mods []*Mod, config Config) (*ModConfig, error) {
 	rimworldVersion := GetRimworldVersion(config)
 	expansions, err := GetRimworldExpansions(config)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	// every installed DLC is known, even inactive ones, so the game doesn't enable them on its own
 	knownExpansions := []string{}
 	for _, expansion := range expansions {
-		if expansion.PackageID == "Ludeon.RimWorld" {
+		if expansion.PackageID == CorePackageID {
 			continue
 		}
 		knownExpansions = append(knownExpansions, string(expansion.PackageID))
@@ -36,42 +78,211 @@ func SetModlist(mods []*Mod, config Config) error {
 		activeMods = append(activeMods, string(mod.PackageID))
 	}
 
-	modConfig := ModConfig{
+	return &ModConfig{
 		Version:         rimworldVersion,
 		KnownExpansions: knownExpansions,
 		ActiveMods:      activeMods,
+	}, nil
+}
+
+// a single <name><li>..</li></name> node of ModsConfig.xml
+type modConfigList struct {
+	XMLName xml.Name
+	Items   []string `xml:"li"`
+}
+
+// indentation of the line existing[offset] is on, if only whitespace precedes it
+func lineIndent(existing []byte, offset int64) string {
+	start := bytes.LastIndexByte(existing[:offset], '\n') + 1
+	indent := existing[start:offset]
+	if len(bytes.TrimLeft(indent, " \t")) > 0 {
+		return ""
 	}
-	modsConfigXml, err := xml.MarshalIndent(modConfig, "", "    ")
+	return string(indent)
+}
+
+// replaces the activeMods and knownExpansions nodes of an existing ModsConfig.xml,
+// leaving everything else (version, other tools' elements, comments) as it was.
+// nodes that are missing are added at the end of the root element
+func SpliceModConfig(existing []byte, modConfig *ModConfig) ([]byte, error) {
+	type replacement struct {
+		start, end int64
+		name       string
+		items      []string
+	}
+	lists := map[string][]string{
+		"knownExpansions": modConfig.KnownExpansions,
+		"activeMods":      modConfig.ActiveMods,
+	}
+
+	// RawToken below doesn't check that tags match, so make sure the file is sound first
+	if err := xml.Unmarshal(existing, &ModConfig{}); err != nil {
+		return nil, err
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(existing))
+	replacements := []replacement{}
+	var rootEnd int64 = -1
+	// indentation of the root's children, used for nodes that have to be added
+	unit := ""
+	depth := 0
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 && unit == "" {
+				unit = lineIndent(existing, offset)
+			}
+			items, ok := lists[token.Name.Local]
+			if depth != 2 || !ok {
+				continue
+			}
+			for inner := 1; inner > 0; {
+				token, err := decoder.RawToken()
+				if err == io.EOF {
+					return nil, io.ErrUnexpectedEOF
+				} else if err != nil {
+					return nil, err
+				}
+				switch token.(type) {
+				case xml.StartElement:
+					inner++
+				case xml.EndElement:
+					inner--
+				}
+			}
+			depth--
+			replacements = append(replacements, replacement{offset, decoder.InputOffset(), token.Name.Local, items})
+			delete(lists, token.Name.Local)
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				rootEnd = offset
+			}
+		}
+	}
+	if rootEnd < 0 {
+		return nil, fmt.Errorf("ModsConfig.xml has no complete root element")
+	}
+	for _, name := range []string{"knownExpansions", "activeMods"} {
+		if items, ok := lists[name]; ok {
+			replacements = append(replacements, replacement{rootEnd, rootEnd, name, items})
+		}
+	}
+
+	if unit == "" {
+		unit = "    "
+	}
+
+	result := []byte{}
+	var last int64 = 0
+	for _, r := range replacements {
+		// the existing indentation before r.start is kept, so the node's first line goes without it
+		indent := lineIndent(existing, r.start)
+		inserted := r.start == rootEnd
+		if inserted {
+			indent += unit
+		}
+		node, err := xml.MarshalIndent(modConfigList{XMLName: xml.Name{Local: r.name}, Items: r.items}, indent, unit)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, existing[last:r.start]...)
+		if inserted {
+			result = append(result, unit...)
+		}
+		result = append(result, bytes.TrimPrefix(node, []byte(indent))...)
+		if inserted {
+			result = append(result, '\n')
+			result = append(result, lineIndent(existing, rootEnd)...)
+		}
+		last = r.end
+	}
+	return append(result, existing[last:]...), nil
+}
+
+// writes ModsConfig.xml atomically, keeping the previous one in the history under list.
+// an existing file only has its mod and expansion lists replaced
+func SetModlist(mods []*Mod, config Config, list string) error {
+	modConfig, err := BuildModConfig(mods, config)
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(filepath.Join(config.RimworldData, "Config/ModsConfig.xml"), modsConfigXml, 0644)
+	existing, err := os.ReadFile(GetModsConfigPath(config))
+	if err == nil {
+		modsConfigXml, err := SpliceModConfig(existing, modConfig)
+		if err == nil {
+			return WriteModsConfig(modsConfigXml, config, list)
+		}
+		fmt.Printf("Couldn't read the existing ModsConfig.xml, replacing it: %v\n", err)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	modsConfigXml, err := xml.MarshalIndent(modConfig, "", "    ")
 	if err != nil {
 		return err
 	}
+	return WriteModsConfig(modsConfigXml, config, list)
+}
 
-	return nil
+type LoadOptions struct {
+	// activate the list even if it contains mods declared incompatible with each other
+	AllowIncompatible bool
+	// tie-break order for sorting, see SortOrder
+	Order []PackageID
+	// only report what would change in Mods and ModsConfig.xml
+	DryRun bool
+	// recorded with the written ModsConfig.xml in the history
+	ListName string
 }
 
-func LoadModlist(mods []*Mod, config Config) error {
-	LinkMods(mods)
-	err := CheckDeps(mods, config)
+func LoadModlist(mods []*Mod, config Config, opts LoadOptions) error {
+	rules, err := LoadRules()
+	if err != nil {
+		return err
+	}
+	LinkMods(mods, rules)
+	for _, rule := range rules.Applied(mods) {
+		fmt.Printf("Applied rule: %s\n", rule)
+	}
+	err = CheckDeps(mods, config)
 	if err != nil {
 		return err
 	}
+	err = CheckIncompatible(mods, rules)
+	if err != nil {
+		if !opts.AllowIncompatible {
+			return err
+		}
+		fmt.Println("Loading anyway:", err)
+	}
 
-	err = SymlinkMods(mods, config)
+	sortedMods, err := SortMods(mods, rules, opts.Order)
 	if err != nil {
 		return err
 	}
-	sortedMods, err := SortMods(mods)
+	summary, err := ActivateMods(sortedMods, config, opts.DryRun)
+	fmt.Println(summary)
+	if opts.DryRun {
+		if err != nil {
+			fmt.Println(err)
+		}
+		fmt.Printf("Would write ModsConfig.xml with %d mods\n", len(sortedMods))
+		return nil
+	}
 	if err != nil {
 		return err
 	}
-
-	SetModlist(sortedMods, config)
-	return nil
+	return SetModlist(sortedMods, config, opts.ListName)
 }
 
 func GetModsFromPath(path string, config Config) ([]*Mod, error) {
@@ -103,9 +314,10 @@ func GetModsFromPath(path string, config Config) ([]*Mod, error) {
 			continue
 		}
 		if _, ok := paths[path]; ok {
-			fmt.Printf("Duplicate path %s, skipping\n", path)
+			fmt.Fprintf(os.Stderr, "Duplicate path %s, skipping\n", path)
 			continue
 		}
+		paths[path] = struct{}{}
 
 		mod, err := ParseMod(path, config)
 		if err != nil {
@@ -115,5 +327,10 @@ func GetModsFromPath(path string, config Config) ([]*Mod, error) {
 
 		mods = append(mods, mod)
 	}
+	// a list naming two copies from the same source is ambiguous, so don't guess which one was meant
+	mods, err = DedupeMods(mods, config)
+	if err != nil {
+		return nil, fmt.Errorf("%w, remove all but one of their paths from %s", err, path)
+	}
 	return mods, nil
 }
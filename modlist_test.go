@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+var spliceModConfig = &ModConfig{
+	Version:         "1.6.4633 rev1273",
+	KnownExpansions: []string{"ludeon.rimworld.royalty", "ludeon.rimworld.anomaly"},
+	ActiveMods:      []string{"brrainz.harmony", "ludeon.rimworld", "ludeon.rimworld.anomaly", "author.mod"},
+}
+
+// the two list nodes, with the indentation before them and the newline after
+var modConfigLists = regexp.MustCompile(`(?s)[ \t]*(<activeMods\s*/>|<activeMods>.*?</activeMods>|<knownExpansions\s*/>|<knownExpansions>.*?</knownExpansions>)\n?`)
+
+func TestSpliceModConfig(t *testing.T) {
+	for _, name := range []string{"real", "comments", "selfclosing", "noknown", "tabs"} {
+		t.Run(name, func(t *testing.T) {
+			existing, err := os.ReadFile(filepath.Join("testdata", "modsconfig_"+name+".xml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join("testdata", "modsconfig_"+name+".want.xml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := SpliceModConfig(existing, spliceModConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+
+			outsideBefore := modConfigLists.ReplaceAll(existing, nil)
+			outsideAfter := modConfigLists.ReplaceAll(got, nil)
+			if string(outsideBefore) != string(outsideAfter) {
+				t.Errorf("content outside the lists changed:\n%s\nwas:\n%s", outsideAfter, outsideBefore)
+			}
+
+			var parsed ModConfig
+			if err := xml.Unmarshal(got, &parsed); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(parsed.ActiveMods, spliceModConfig.ActiveMods) {
+				t.Errorf("activeMods = %v, want %v", parsed.ActiveMods, spliceModConfig.ActiveMods)
+			}
+			if !slices.Equal(parsed.KnownExpansions, spliceModConfig.KnownExpansions) {
+				t.Errorf("knownExpansions = %v, want %v", parsed.KnownExpansions, spliceModConfig.KnownExpansions)
+			}
+		})
+	}
+}
+
+func TestSpliceModConfigIdempotent(t *testing.T) {
+	existing, err := os.ReadFile(filepath.Join("testdata", "modsconfig_real.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	once, err := SpliceModConfig(existing, spliceModConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	twice, err := SpliceModConfig(once, spliceModConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(once) != string(twice) {
+		t.Errorf("splicing twice changed the file:\n%s\nfirst:\n%s", twice, once)
+	}
+}
+
+func TestSpliceModConfigMalformed(t *testing.T) {
+	for _, existing := range []string{"", "<ModsConfigData><activeMods>", "<ModsConfigData></wrong>"} {
+		if _, err := SpliceModConfig([]byte(existing), spliceModConfig); err == nil {
+			t.Errorf("no error for %q", existing)
+		}
+	}
+}
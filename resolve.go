@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type UnmetDep struct {
+	Mod   *Mod
+	Group []PackageID
+}
+
+// picks which of candidates (in the order of the dependency's alternatives) should satisfy mod's dependency
+type ProviderChooser func(mod *Mod, candidates []*Mod) *Mod
+
+func PreferFirstProvider(mod *Mod, candidates []*Mod) *Mod {
+	return candidates[0]
+}
+
+func PromptProvider(mod *Mod, candidates []*Mod) *Mod {
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	fmt.Printf("%s needs one of:\n", mod.PackageID)
+	for i, candidate := range candidates {
+		fmt.Printf("  %d) %s\n", i+1, candidate)
+	}
+	for {
+		answer := Prompt(fmt.Sprintf("Choose [1-%d, default 1]:", len(candidates)))
+		if answer == "" {
+			return candidates[0]
+		}
+		i, err := strconv.Atoi(answer)
+		if err == nil && i >= 1 && i <= len(candidates) {
+			return candidates[i-1]
+		}
+	}
+}
+
+// adds providers from pool until every dependency of mods (and of the added mods) is satisfied.
+// returns the added mods and the dependencies nothing in pool can satisfy
+func ResolveDeps(mods []*Mod, pool []*Mod, choose ProviderChooser) ([]*Mod, []UnmetDep) {
+	poolByPid := map[PackageID]*Mod{}
+	for _, mod := range pool {
+		if _, ok := poolByPid[mod.PackageID]; !ok {
+			poolByPid[mod.PackageID] = mod
+		}
+	}
+	active := map[PackageID]bool{}
+	for _, mod := range mods {
+		active[mod.PackageID] = true
+	}
+
+	added := []*Mod{}
+	unmet := []UnmetDep{}
+	queue := slices.Clone(mods)
+	for len(queue) > 0 {
+		mod := queue[0]
+		queue = queue[1:]
+		for _, group := range mod.Deps {
+			if slices.ContainsFunc(group, func(pid PackageID) bool { return active[pid] }) {
+				continue
+			}
+			candidates := []*Mod{}
+			for _, pid := range group {
+				if candidate, ok := poolByPid[pid]; ok {
+					candidates = append(candidates, candidate)
+				}
+			}
+			if len(candidates) == 0 {
+				unmet = append(unmet, UnmetDep{Mod: mod, Group: group})
+				continue
+			}
+			provider := choose(mod, candidates)
+			fmt.Printf("Using %s for %s\n", provider, mod.PackageID)
+			active[provider.PackageID] = true
+			added = append(added, provider)
+			queue = append(queue, provider)
+		}
+	}
+	return added, unmet
+}
+
+func (dep UnmetDep) String() string {
+	pids := []string{}
+	for _, pid := range dep.Group {
+		pids = append(pids, string(pid))
+	}
+	return fmt.Sprintf("%s needs one of %s", dep.Mod.PackageID, strings.Join(pids, ", "))
+}
+
+// workshop item linked by the About.xml entry for this dependency, if any
+func (dep UnmetDep) WorkshopID() (SteamID, bool) {
+	for _, entry := range dep.Mod.ModDependenciesFull() {
+		if !slices.Contains(dep.Group, PackageID(strings.ToLower(entry.PackageID))) {
+			continue
+		}
+		if id, ok := ParseWorkshopURL(entry.SteamWorkshopURL); ok {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// accepts both https://steamcommunity.com/sharedfiles/filedetails/?id=123 and steam://url/CommunityFilePage/123
+func ParseWorkshopURL(raw string) (SteamID, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || raw == "" {
+		return 0, false
+	}
+	idStr := parsed.Query().Get("id")
+	if idStr == "" {
+		idStr = path.Base(parsed.Path)
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return SteamID(id), true
+}
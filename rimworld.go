@@ -2,24 +2,45 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 )
 
+const defaultRimworldVersion = "1.6.4633 rev1273"
+
+var majorVersionPattern = regexp.MustCompile(`^\d+\.\d+`)
+
+// Version.txt by TargetDir, it is read for every parsed mod
+var rimworldVersions = map[string]string{}
+
 func GetRimworldVersion(config Config) string {
+	if version, ok := rimworldVersions[config.TargetDir]; ok {
+		return version
+	}
+	version := defaultRimworldVersion
 	content, err := os.ReadFile(filepath.Join(config.TargetDir, "Version.txt"))
-	if err != nil {
-		return "1.6.4633 rev1273"
+	if err == nil {
+		version = strings.TrimSpace(string(content))
+		if !majorVersionPattern.MatchString(version) {
+			fmt.Fprintf(os.Stderr, "Can't read a version from Version.txt (%q), assuming %s\n", version, defaultRimworldVersion)
+			version = defaultRimworldVersion
+		}
 	}
-	return strings.TrimRight(string(content), "\r\n")
+	rimworldVersions[config.TargetDir] = version
+	return version
 }
 
+// "1.6" for "1.6.4633 rev1273"
 func GetRimworldMajorVersion(config Config) string {
-	return GetRimworldVersion(config)[:3]
+	return majorVersionPattern.FindString(GetRimworldVersion(config))
 }
 
+const CorePackageID PackageID = "ludeon.rimworld"
+
+// core and the DLCs in the game's Data folder. folders that don't parse are skipped
 func GetRimworldExpansions(config Config) ([]*Mod, error) {
 	expansionPath := filepath.Join(config.TargetDir, "Data")
 	modPaths, err := os.ReadDir(expansionPath)
@@ -35,45 +56,47 @@ func GetRimworldExpansions(config Config) ([]*Mod, error) {
 		subdirPath := filepath.Join(expansionPath, entry.Name())
 		expansion, err := ParseMod(subdirPath, config)
 		if err != nil {
-			fmt.Printf("Unsuccessful parse on %s @ %s\n", entry.Name(), subdirPath)
+			fmt.Fprintf(os.Stderr, "Unsuccessful parse on %s @ %s, skipping: %v\n", entry.Name(), subdirPath, err)
+			continue
 		}
 		expansions = append(expansions, expansion)
 	}
 	return expansions, nil
 }
 
-func SymlinkMods(mods []*Mod, config Config) error {
-	modsDir := filepath.Join(config.TargetDir, "Mods")
+// whether name refers to the DLC, by package ID ("ludeon.rimworld.anomaly"),
+// the part after "ludeon.rimworld." ("anomaly"), its Data folder or its name
+func (mod *Mod) IsDLC(name string) bool {
+	name = strings.ToLower(name)
+	return mod.Source == ModSourceOfficial && mod.PackageID != CorePackageID && (string(mod.PackageID) == name ||
+		string(mod.PackageID) == string(CorePackageID)+"."+name ||
+		strings.ToLower(filepath.Base(mod.Path)) == name ||
+		strings.ToLower(mod.About.Name) == name)
+}
 
-	entries, err := os.ReadDir(modsDir)
+// drops the DLCs named in without from mods. core can't be dropped,
+// and naming something that isn't an installed DLC is an error
+func WithoutDLCs(mods []*Mod, without []string, config Config) ([]*Mod, error) {
+	if len(without) == 0 {
+		return mods, nil
+	}
+	expansions, err := GetRimworldExpansions(config)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	for _, entry := range entries {
-		path := filepath.Join(modsDir, entry.Name())
-		info, err := os.Lstat(path)
-		if err != nil {
-			fmt.Println("Error getting info for", path, ":", err)
-			continue
-		}
-
-		if info.Mode()&os.ModeSymlink != 0 {
-			if err := os.Remove(path); err != nil {
-				fmt.Println("Failed to unlink mod: ", err)
-			}
+	for _, name := range without {
+		if !slices.ContainsFunc(expansions, func(expansion *Mod) bool { return expansion.IsDLC(name) }) {
+			return nil, fmt.Errorf("%q is not an installed DLC", name)
 		}
 	}
 
+	kept := []*Mod{}
 	for _, mod := range mods {
-		if mod.Source == ModSourceOfficial {
+		if slices.ContainsFunc(without, mod.IsDLC) {
+			fmt.Printf("Leaving out %s\n", mod)
 			continue
 		}
-		err := os.Symlink(mod.Path, filepath.Join(config.TargetDir, "Mods/"+string(mod.PackageID)))
-		if err != nil {
-			log.Printf("Failed to link %s\n", mod)
-			log.Println(err)
-		}
+		kept = append(kept, mod)
 	}
-
-	return nil
+	return kept, nil
 }
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetRimworldMajorVersion(t *testing.T) {
+	for content, want := range map[string]string{
+		"1.6.4633 rev1273\r\n": "1.6",
+		"1.10.123 rev5":        "1.10",
+		"1":                    "1.6",
+		"":                     "1.6",
+		"garbage":              "1.6",
+	} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "Version.txt"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := GetRimworldMajorVersion(Config{TargetDir: dir}); got != want {
+			t.Errorf("%q: got %q, want %q", content, got, want)
+		}
+	}
+	if got := GetRimworldMajorVersion(Config{TargetDir: t.TempDir()}); got != "1.6" {
+		t.Errorf("without Version.txt: got %q, want 1.6", got)
+	}
+}
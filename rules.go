@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const communityRulesFile = "communityRules.json"
+const userRulesFile = "rules.toml"
+
+type RuleKind int
+
+const (
+	RuleLoadAfter RuleKind = iota
+	RuleLoadBefore
+	RuleLoadTop
+	RuleLoadBottom
+	RuleIncompatibleWith
+	// drops a loadAfter/loadBefore edge between Mod and Other declared in About.xml or community rules
+	RuleSuppress
+)
+
+type Rule struct {
+	Kind RuleKind
+	Mod  PackageID
+	// unset for RuleLoadTop and RuleLoadBottom
+	Other PackageID
+	// file the rule was read from
+	Source string
+}
+
+type Rules []Rule
+
+func (rule Rule) String() string {
+	var desc string
+	switch rule.Kind {
+	case RuleLoadAfter:
+		desc = fmt.Sprintf("%s loads after %s", rule.Mod, rule.Other)
+	case RuleLoadBefore:
+		desc = fmt.Sprintf("%s loads before %s", rule.Mod, rule.Other)
+	case RuleLoadTop:
+		desc = fmt.Sprintf("%s loads at the top", rule.Mod)
+	case RuleLoadBottom:
+		desc = fmt.Sprintf("%s loads at the bottom", rule.Mod)
+	case RuleIncompatibleWith:
+		desc = fmt.Sprintf("%s is incompatible with %s", rule.Mod, rule.Other)
+	case RuleSuppress:
+		desc = fmt.Sprintf("ignore load order between %s and %s", rule.Mod, rule.Other)
+	}
+	return fmt.Sprintf("%s (%s)", desc, rule.Source)
+}
+
+// the load-top or load-bottom rule that decides pid's layer, if any.
+// rules.toml is the local override, so its tiers win over communityRules.json's
+func (rules Rules) Tier(pid PackageID) (Rule, bool) {
+	var tier Rule
+	found := false
+	for _, rule := range rules {
+		if (rule.Kind != RuleLoadTop && rule.Kind != RuleLoadBottom) || rule.Mod != pid {
+			continue
+		}
+		if !found || (tier.Source != userRulesFile && rule.Source == userRulesFile) {
+			tier = rule
+			found = true
+		}
+	}
+	return tier, found
+}
+
+// RimSort's format, every rule entry is an object of comments and names we don't care about
+type communityRulesDB struct {
+	Rules map[string]communityRule `json:"rules"`
+}
+
+type communityRule struct {
+	LoadAfter        map[string]json.RawMessage `json:"loadAfter"`
+	LoadBefore       map[string]json.RawMessage `json:"loadBefore"`
+	IncompatibleWith map[string]json.RawMessage `json:"incompatibleWith"`
+	LoadTop          struct {
+		Value bool `json:"value"`
+	} `json:"loadTop"`
+	LoadBottom struct {
+		Value bool `json:"value"`
+	} `json:"loadBottom"`
+}
+
+func LoadCommunityRules() (Rules, error) {
+	data, err := os.ReadFile(filepath.Join(GetConfigPath(), communityRulesFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var db communityRulesDB
+	if err := json.Unmarshal(data, &db); err != nil {
+		return nil, err
+	}
+
+	rules := Rules{}
+	for _, key := range slices.Sorted(maps.Keys(db.Rules)) {
+		entry := db.Rules[key]
+		pid := PackageID(strings.ToLower(key))
+		edges := []struct {
+			kind    RuleKind
+			targets map[string]json.RawMessage
+		}{
+			{RuleLoadAfter, entry.LoadAfter},
+			{RuleLoadBefore, entry.LoadBefore},
+			{RuleIncompatibleWith, entry.IncompatibleWith},
+		}
+		for _, edge := range edges {
+			for _, other := range slices.Sorted(maps.Keys(edge.targets)) {
+				rules = append(rules, Rule{
+					Kind:   edge.kind,
+					Mod:    pid,
+					Other:  PackageID(strings.ToLower(other)),
+					Source: communityRulesFile,
+				})
+			}
+		}
+		if entry.LoadTop.Value {
+			rules = append(rules, Rule{Kind: RuleLoadTop, Mod: pid, Source: communityRulesFile})
+		}
+		if entry.LoadBottom.Value {
+			rules = append(rules, Rule{Kind: RuleLoadBottom, Mod: pid, Source: communityRulesFile})
+		}
+	}
+	return rules, nil
+}
+
+func (rules Rules) Suppressed(a PackageID, b PackageID) bool {
+	for _, rule := range rules {
+		if rule.Kind != RuleSuppress {
+			continue
+		}
+		if (rule.Mod == a && rule.Other == b) || (rule.Mod == b && rule.Other == a) {
+			return true
+		}
+	}
+	return false
+}
+
+// rules that refer only to mods in the list
+func (rules Rules) Applied(mods []*Mod) Rules {
+	pids := map[PackageID]struct{}{}
+	for _, mod := range mods {
+		pids[mod.PackageID] = struct{}{}
+	}
+	applied := Rules{}
+	for _, rule := range rules {
+		if _, ok := pids[rule.Mod]; !ok {
+			continue
+		}
+		if rule.Kind == RuleLoadTop || rule.Kind == RuleLoadBottom {
+			if tier, _ := rules.Tier(rule.Mod); tier != rule {
+				continue
+			}
+		} else if _, ok := pids[rule.Other]; !ok {
+			continue
+		}
+		applied = append(applied, rule)
+	}
+	return applied
+}
+
+type UserRules struct {
+	LoadTop    []string            `toml:"load-top" comment:"Package IDs to always load before everything else"`
+	LoadBottom []string            `toml:"load-bottom" comment:"Package IDs to always load after everything else"`
+	LoadAfter  map[string][]string `toml:"load-after" comment:"Extra edges, e.g. \"some.mod\" = [\"loads.before.it\"]"`
+	LoadBefore map[string][]string `toml:"load-before" comment:"Extra edges, e.g. \"some.mod\" = [\"loads.after.it\"]"`
+	Suppress   map[string][]string `toml:"suppress" comment:"Ignore load order a mod author declared between two mods, e.g. \"some.mod\" = [\"other.mod\"]"`
+}
+
+func LoadUserRules() (Rules, error) {
+	configRoot := GetConfigPath()
+	rulesPath := filepath.Join(configRoot, userRulesFile)
+	data, err := os.ReadFile(rulesPath)
+
+	var userRules UserRules
+	if errors.Is(err, os.ErrNotExist) {
+		os.MkdirAll(configRoot, 0755)
+		userRules = UserRules{
+			LoadTop:    []string{},
+			LoadBottom: []string{"vr.missilegirl", "taranchuk.performanceoptimizer"},
+			LoadAfter:  map[string][]string{},
+			LoadBefore: map[string][]string{},
+			Suppress:   map[string][]string{},
+		}
+		data, err = toml.Marshal(userRules)
+		if err != nil {
+			return nil, err
+		}
+		err = os.WriteFile(rulesPath, data, 0644)
+		if err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := toml.Unmarshal(data, &userRules); err != nil {
+		return nil, fmt.Errorf("%s: %w", rulesPath, err)
+	}
+
+	rules := Rules{}
+	for _, pid := range userRules.LoadTop {
+		rules = append(rules, Rule{Kind: RuleLoadTop, Mod: PackageID(strings.ToLower(pid)), Source: userRulesFile})
+	}
+	for _, pid := range userRules.LoadBottom {
+		rules = append(rules, Rule{Kind: RuleLoadBottom, Mod: PackageID(strings.ToLower(pid)), Source: userRulesFile})
+	}
+	edges := []struct {
+		kind    RuleKind
+		targets map[string][]string
+	}{
+		{RuleLoadAfter, userRules.LoadAfter},
+		{RuleLoadBefore, userRules.LoadBefore},
+		{RuleSuppress, userRules.Suppress},
+	}
+	for _, edge := range edges {
+		for _, key := range slices.Sorted(maps.Keys(edge.targets)) {
+			for _, other := range edge.targets[key] {
+				rules = append(rules, Rule{
+					Kind:   edge.kind,
+					Mod:    PackageID(strings.ToLower(key)),
+					Other:  PackageID(strings.ToLower(other)),
+					Source: userRulesFile,
+				})
+			}
+		}
+	}
+	return rules, nil
+}
+
+func LoadRules() (Rules, error) {
+	community, err := LoadCommunityRules()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", communityRulesFile, err)
+	}
+	user, err := LoadUserRules()
+	if err != nil {
+		return nil, err
+	}
+	return append(community, user...), nil
+}
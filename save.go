@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrNoSaveMeta = errors.New("No <meta> found in save")
+
+type SaveMeta struct {
+	GameVersion string   `xml:"gameVersion"`
+	ModIDs      []string `xml:"modIds>li"`
+	ModSteamIDs []string `xml:"modSteamIds>li"`
+	ModNames    []string `xml:"modNames>li"`
+}
+
+// saves can be tens of megabytes, so only decode until the <meta> header is read
+func ReadSaveMeta(path string) (*SaveMeta, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := xml.NewDecoder(file)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, ErrNoSaveMeta
+		} else if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "meta" {
+			continue
+		}
+		var meta SaveMeta
+		if err := decoder.DecodeElement(&meta, &start); err != nil {
+			return nil, err
+		}
+		return &meta, nil
+	}
+}
+
+// steam ID recorded for the mod at index i, 0 for local and official mods
+func (meta *SaveMeta) SteamID(i int) SteamID {
+	if i >= len(meta.ModSteamIDs) {
+		return 0
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(meta.ModSteamIDs[i]))
+	if err != nil {
+		return 0
+	}
+	return SteamID(id)
+}
+
+func (meta *SaveMeta) SteamIDs() []SteamID {
+	ids := []SteamID{}
+	for i := range meta.ModSteamIDs {
+		ids = append(ids, meta.SteamID(i))
+	}
+	return ids
+}
+
+// steam IDs of mods in the save that are neither installed nor downloaded to SteamModSrc
+func (meta *SaveMeta) MissingSteamIDs(missing []PackageID, config Config) []SteamID {
+	ids := []SteamID{}
+	for i, raw := range meta.ModIDs {
+		pid := PackageID(strings.ToLower(strings.TrimSpace(raw)))
+		if !slices.Contains(missing, pid) {
+			continue
+		}
+		id := meta.SteamID(i)
+		if id == 0 {
+			continue
+		}
+		if _, err := os.Stat(SteamModPath(config, id)); err == nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
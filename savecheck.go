@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type SaveDiff struct {
+	Removed []PackageID
+	Added   []PackageID
+	// mods in both whose relative order changed
+	Reordered []PackageID
+	// removed package ID -> mods from the save that needed it and have no other provider in the list
+	Broken map[PackageID][]*Mod
+}
+
+// positions (indices into values) of a longest increasing subsequence
+func longestIncreasing(values []int) map[int]bool {
+	tails := []int{}
+	prev := make([]int, len(values))
+	for i, v := range values {
+		j, _ := slices.BinarySearchFunc(tails, v, func(t int, v int) int { return values[t] - v })
+		if j > 0 {
+			prev[i] = tails[j-1]
+		} else {
+			prev[i] = -1
+		}
+		if j == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[j] = i
+		}
+	}
+	kept := map[int]bool{}
+	if len(tails) == 0 {
+		return kept
+	}
+	for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+		kept[i] = true
+	}
+	return kept
+}
+
+// compares the save's mods against sorted, the list it would be loaded with.
+// pool is used to find the About.xml of removed mods
+func CompareSave(meta *SaveMeta, sorted []*Mod, pool []*Mod) SaveDiff {
+	diff := SaveDiff{Broken: map[PackageID][]*Mod{}}
+
+	listPos := map[PackageID]int{}
+	for i, mod := range sorted {
+		listPos[mod.PackageID] = i
+	}
+	savePids := []PackageID{}
+	inSave := map[PackageID]bool{}
+	for _, raw := range meta.ModIDs {
+		pid, _ := NormalizeModID(raw)
+		if pid == "" || inSave[pid] {
+			continue
+		}
+		savePids = append(savePids, pid)
+		inSave[pid] = true
+	}
+
+	common := []PackageID{}
+	positions := []int{}
+	for _, pid := range savePids {
+		pos, ok := listPos[pid]
+		if !ok {
+			diff.Removed = append(diff.Removed, pid)
+			continue
+		}
+		common = append(common, pid)
+		positions = append(positions, pos)
+	}
+	for _, mod := range sorted {
+		if !inSave[mod.PackageID] {
+			diff.Added = append(diff.Added, mod.PackageID)
+		}
+	}
+	kept := longestIncreasing(positions)
+	for i, pid := range common {
+		if !kept[i] {
+			diff.Reordered = append(diff.Reordered, pid)
+		}
+	}
+
+	saveMods, _ := ResolvePackageIDs(meta.ModIDs, meta.SteamIDs(), append(slices.Clone(sorted), pool...))
+	for _, removed := range diff.Removed {
+		for _, mod := range saveMods {
+			for _, group := range mod.Deps {
+				if !slices.Contains(group, removed) {
+					continue
+				}
+				satisfied := false
+				for _, dep := range group {
+					if _, ok := listPos[dep]; ok {
+						satisfied = true
+					}
+				}
+				if !satisfied && !slices.Contains(diff.Broken[removed], mod) {
+					diff.Broken[removed] = append(diff.Broken[removed], mod)
+				}
+			}
+		}
+	}
+	return diff
+}
+
+func (diff SaveDiff) String() string {
+	lines := []string{}
+	for _, pid := range diff.Removed {
+		lines = append(lines, fmt.Sprintf("removed   %s", pid))
+		for _, mod := range diff.Broken[pid] {
+			lines = append(lines, fmt.Sprintf("  needed by %s", mod.PackageID))
+		}
+	}
+	for _, pid := range diff.Added {
+		lines = append(lines, fmt.Sprintf("added     %s", pid))
+	}
+	for _, pid := range diff.Reordered {
+		lines = append(lines, fmt.Sprintf("reordered %s", pid))
+	}
+	if len(lines) == 0 {
+		return "list matches the save"
+	}
+	return strings.Join(lines, "\n")
+}
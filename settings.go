@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/pelletier/go-toml/v2"
 )
@@ -14,6 +15,25 @@ type Config struct {
 	LocalModSrc  string `toml:"local-src"    comment:"Source directory where local and git mods can be found"`
 	RimworldData string `toml:"rimworld-data" comment:"Rimworld's data path (often .config/unity3d/Ludeon Studios/RimWorld by Ludeon Studios/)"`
 	TargetDir    string `toml:"target-dir" comment:"Rimworld path"`
+	Activation   string `toml:"activation" comment:"How mods are put in Rimworld's Mods folder: symlink, hardlink (a tree of hardlinked files) or copy"`
+	// unset in older configs, see SourceRank
+	SourcePreference []string `toml:"source-preference" comment:"Which copy of a mod to use when several share a PackageID, most preferred first (local, git, steam, official)"`
+}
+
+var defaultSourcePreference = []string{"local", "git", "steam"}
+
+// lower is preferred, sources missing from the preference rank last
+func (config Config) SourceRank(source ModSource) int {
+	preference := config.SourcePreference
+	if len(preference) == 0 {
+		preference = defaultSourcePreference
+	}
+	for i, name := range preference {
+		if strings.EqualFold(name, source.String()) {
+			return i
+		}
+	}
+	return len(preference)
 }
 
 func GetConfigPath() string {
@@ -42,6 +62,9 @@ func LoadConfig() Config {
 			LocalModSrc:  "/home/dormierian/.config/rimtag/mods",
 			RimworldData: "/home/dormierian/.config/unity3d/Ludeon Studios/RimWorld by Ludeon Studios/",
 			TargetDir:    "/home/dormierian/Games/rimworld",
+			Activation:   string(ActivationSymlink),
+
+			SourcePreference: defaultSourcePreference,
 		}
 		data, err := toml.Marshal(cfg)
 		if err != nil {
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"encoding/csv"
+	"encoding/xml"
 	"fmt"
 	"strings"
 )
@@ -26,6 +27,39 @@ func GetTSV(mods []*Mod) string {
 	return buf.String()
 }
 
+func ExportRimSort(mods []*Mod, config Config) (string, error) {
+	modConfig, err := BuildModConfig(mods, config)
+	if err != nil {
+		return "", err
+	}
+	data, err := xml.MarshalIndent(modConfig, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(data), nil
+}
+
+func ExportRimPy(mods []*Mod, config Config) (string, error) {
+	list := SavedModList{}
+	list.Meta.GameVersion = GetRimworldVersion(config)
+	for _, mod := range mods {
+		name := mod.About.Name
+		if len(name) == 0 {
+			name = string(mod.PackageID)
+		}
+		list.Meta.ModIDs = append(list.Meta.ModIDs, string(mod.PackageID))
+		list.Meta.ModSteamIDs = append(list.Meta.ModSteamIDs, fmt.Sprint(mod.GetPublishedAppID()))
+		list.Meta.ModNames = append(list.Meta.ModNames, name)
+		list.ModList.IDs = append(list.ModList.IDs, string(mod.PackageID))
+		list.ModList.Names = append(list.ModList.Names, name)
+	}
+	data, err := xml.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(data), nil
+}
+
 func ExportMarkdown(mods []*Mod, config Config) string {
 	md := fmt.Sprintf(
 		`# RimWorld Mod List: %[1]d mods       ![](https://github.com/RimSort/RimSort/blob/main/docs/rentry_preview.png?raw=true)
@@ -7,34 +7,78 @@ import (
 	"strings"
 )
 
-var LateLoaders = []string{"vr.missilegirl", "taranchuk.performanceoptimizer"}
-var EarlyLoaders = []string{}
+func linkAfter(mod *Mod, target *Mod, origin EdgeOrigin) {
+	if !slices.Contains(mod.LoadAfter, target) {
+		mod.LoadAfter = append(mod.LoadAfter, target)
+	}
+	if mod.LoadAfterOrigins == nil {
+		mod.LoadAfterOrigins = map[*Mod][]EdgeOrigin{}
+	}
+	if !slices.Contains(mod.LoadAfterOrigins[target], origin) {
+		mod.LoadAfterOrigins[target] = append(mod.LoadAfterOrigins[target], origin)
+	}
+}
 
-func LinkMods(mods []*Mod) {
+func LinkMods(mods []*Mod, rules Rules) {
 	modsByPid := map[PackageID]*Mod{}
 	for _, mod := range mods {
 		modsByPid[mod.PackageID] = mod
 	}
 	for _, mod := range mods {
-		for _, pid := range mod.LoadAfterFull() {
-			if target, ok := modsByPid[pid]; ok {
-				if !slices.Contains(mod.LoadAfter, target) {
-					mod.LoadAfter = append(mod.LoadAfter, target)
-				}
+		for _, edge := range mod.LoadAfterEdges() {
+			if rules.Suppressed(mod.PackageID, edge.PackageID) {
+				continue
+			}
+			if target, ok := modsByPid[edge.PackageID]; ok {
+				linkAfter(mod, target, edge.Origin)
 			}
 		}
-		for _, pid := range mod.LoadBeforeFull() {
-			if target, ok := modsByPid[pid]; ok {
-				if !slices.Contains(target.LoadAfter, mod) {
-					target.LoadAfter = append(target.LoadAfter, mod)
-				}
+		for _, edge := range mod.LoadBeforeEdges() {
+			if rules.Suppressed(mod.PackageID, edge.PackageID) {
+				continue
+			}
+			if target, ok := modsByPid[edge.PackageID]; ok {
+				linkAfter(target, mod, edge.Origin)
 			}
 		}
 	}
+	for _, rule := range rules {
+		mod, ok := modsByPid[rule.Mod]
+		if !ok {
+			continue
+		}
+		target, ok := modsByPid[rule.Other]
+		if !ok {
+			continue
+		}
+		if rule.Source != userRulesFile && rules.Suppressed(rule.Mod, rule.Other) {
+			continue
+		}
+		switch rule.Kind {
+		case RuleLoadAfter:
+			linkAfter(mod, target, EdgeOrigin(rule.Source))
+		case RuleLoadBefore:
+			linkAfter(target, mod, EdgeOrigin(rule.Source))
+		}
+	}
+}
+
+// human readable reason for mod loading after target
+func DescribeEdge(mod *Mod, target *Mod, origin EdgeOrigin) string {
+	switch {
+	case origin == OriginCore:
+		return fmt.Sprintf("%s loads after %s (%s)", mod.PackageID, target.PackageID, origin)
+	case origin == userRulesFile || origin == communityRulesFile:
+		return fmt.Sprintf("%s loads after %s (rule in %s)", mod.PackageID, target.PackageID, origin)
+	case origin.IsBefore():
+		return fmt.Sprintf("%s loads after %s (%s in %s's About.xml)", mod.PackageID, target.PackageID, origin, target.PackageID)
+	default:
+		return fmt.Sprintf("%s loads after %s (%s in %s's About.xml)", mod.PackageID, target.PackageID, origin, mod.PackageID)
+	}
 }
 
 func CheckDeps(mods []*Mod, config Config) error {
-	version := GetRimworldVersion(config)[:3]
+	version := GetRimworldMajorVersion(config)
 	modsByPid := map[PackageID]*Mod{}
 	for _, mod := range mods {
 		modsByPid[mod.PackageID] = mod
@@ -68,71 +112,176 @@ func CheckDeps(mods []*Mod, config Config) error {
 	}
 }
 
-func findCycle(graph map[*Mod][]*Mod) []*Mod {
-	const (
-		unvisited = 0
-		visiting  = 1
-		visited   = 2
-	)
+func CheckIncompatible(mods []*Mod, rules Rules) error {
+	modsByPid := map[PackageID]*Mod{}
+	for _, mod := range mods {
+		modsByPid[mod.PackageID] = mod
+	}
 
-	state := make(map[*Mod]int)
-	parent := make(map[*Mod]*Mod)
+	type pair struct{ a, b *Mod }
+	declared := map[pair][]string{}
+	pairs := []pair{}
+	declare := func(mod *Mod, pid PackageID, by string) {
+		other, ok := modsByPid[pid]
+		if !ok || other == mod {
+			return
+		}
+		key := pair{mod, other}
+		if other.PackageID < mod.PackageID {
+			key = pair{other, mod}
+		}
+		if _, ok := declared[key]; !ok {
+			pairs = append(pairs, key)
+		}
+		if !slices.Contains(declared[key], by) {
+			declared[key] = append(declared[key], by)
+		}
+	}
+	for _, mod := range mods {
+		for _, pid := range mod.IncompatibleWithFull() {
+			declare(mod, pid, string(mod.PackageID))
+		}
+	}
+	for _, rule := range rules {
+		if rule.Kind != RuleIncompatibleWith {
+			continue
+		}
+		if mod, ok := modsByPid[rule.Mod]; ok {
+			declare(mod, rule.Other, rule.Source)
+		}
+	}
 
-	var cycle []*Mod
-	var dfs func(*Mod) bool
+	for _, p := range pairs {
+		by := declared[p]
+		fmt.Printf("Incompatible mods %s and %s (declared by %s)\n", p.a, p.b, strings.Join(by, ", "))
+	}
+	if len(pairs) > 0 {
+		return fmt.Errorf("%d incompatible mod pairs", len(pairs))
+	}
+	return nil
+}
 
-	dfs = func(u *Mod) bool {
-		state[u] = visiting
+// strongly connected components of the LoadAfter graph restricted to mods, using Tarjan's algorithm.
+// only components that are actually cycles are returned
+func FindCycles(mods []*Mod) [][]*Mod {
+	inLayer := map[*Mod]bool{}
+	for _, mod := range mods {
+		inLayer[mod] = true
+	}
 
-		for _, v := range graph[u] {
-			if state[v] == unvisited {
-				parent[v] = u
-				if dfs(v) {
-					return true
-				}
-			} else if state[v] == visiting {
-				cycle = append(cycle, v)
-				for x := u; x != v; x = parent[x] {
-					cycle = append(cycle, x)
-				}
-				cycle = append(cycle, v)
-				return true
+	index := map[*Mod]int{}
+	lowlink := map[*Mod]int{}
+	onStack := map[*Mod]bool{}
+	stack := []*Mod{}
+	next := 0
+	cycles := [][]*Mod{}
+
+	var strongConnect func(*Mod)
+	strongConnect = func(u *Mod) {
+		index[u] = next
+		lowlink[u] = next
+		next++
+		stack = append(stack, u)
+		onStack[u] = true
+
+		for _, v := range u.LoadAfter {
+			if !inLayer[v] {
+				continue
+			}
+			if _, seen := index[v]; !seen {
+				strongConnect(v)
+				lowlink[u] = min(lowlink[u], lowlink[v])
+			} else if onStack[v] {
+				lowlink[u] = min(lowlink[u], index[v])
 			}
 		}
 
-		state[u] = visited
-		return false
-	}
-
-	for node := range graph {
-		if state[node] == unvisited {
-			if dfs(node) {
+		if lowlink[u] != index[u] {
+			return
+		}
+		component := []*Mod{}
+		for {
+			v := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[v] = false
+			component = append(component, v)
+			if v == u {
 				break
 			}
 		}
+		if len(component) > 1 || slices.Contains(u.LoadAfter, u) {
+			sort.Slice(component, func(i, j int) bool {
+				return component[i].PackageID < component[j].PackageID
+			})
+			cycles = append(cycles, component)
+		}
 	}
 
-	for i, j := 0, len(cycle)-1; i < j; i, j = i+1, j-1 {
-		cycle[i], cycle[j] = cycle[j], cycle[i]
+	for _, mod := range mods {
+		if _, seen := index[mod]; !seen {
+			strongConnect(mod)
+		}
 	}
+	return cycles
+}
 
-	return cycle
+func describeCycles(cycles [][]*Mod) string {
+	lines := []string{}
+	for i, component := range cycles {
+		names := []string{}
+		for _, mod := range component {
+			names = append(names, string(mod.PackageID))
+		}
+		lines = append(lines, fmt.Sprintf("cycle %d: %s", i+1, strings.Join(names, ", ")))
+		for _, mod := range component {
+			for _, target := range mod.LoadAfter {
+				if !slices.Contains(component, target) {
+					continue
+				}
+				for _, origin := range mod.LoadAfterOrigins[target] {
+					lines = append(lines, "  "+DescribeEdge(mod, target, origin))
+				}
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
 }
 
-func SortLayer(mods []*Mod) ([]*Mod, error) {
+// ties are broken by position in order, mods not in order come after in alphabetical order.
+// a nil order sorts ties purely alphabetically
+func SortLayer(mods []*Mod, order []PackageID) ([]*Mod, error) {
+	priority := make(map[PackageID]int)
+	for i, pid := range order {
+		if _, ok := priority[pid]; !ok {
+			priority[pid] = i
+		}
+	}
+	less := func(a *Mod, b *Mod) bool {
+		pa, okA := priority[a.PackageID]
+		pb, okB := priority[b.PackageID]
+		if okA != okB {
+			return okA
+		}
+		if okA && pa != pb {
+			return pa < pb
+		}
+		return a.PackageID < b.PackageID
+	}
+
 	indegree := make(map[*Mod]int)
 	graph := make(map[*Mod][]*Mod)
 
 	for _, mod := range mods {
-		if _, ok := indegree[mod]; !ok {
-			indegree[mod] = 0
-		}
+		indegree[mod] = 0
+	}
+	for _, mod := range mods {
 		for _, dep := range mod.LoadAfter {
-			graph[dep] = append(graph[dep], mod)
-			indegree[mod]++
+			// edges into earlier layers are satisfied by the layer order, SortMods rejects the rest
 			if _, ok := indegree[dep]; !ok {
-				indegree[dep] = 0
+				continue
 			}
+			graph[dep] = append(graph[dep], mod)
+			indegree[mod]++
 		}
 	}
 
@@ -144,7 +293,7 @@ func SortLayer(mods []*Mod) ([]*Mod, error) {
 	}
 
 	sort.Slice(ready, func(i, j int) bool {
-		return ready[i].PackageID < ready[j].PackageID
+		return less(ready[i], ready[j])
 	})
 
 	var result []*Mod
@@ -163,62 +312,98 @@ func SortLayer(mods []*Mod) ([]*Mod, error) {
 		}
 
 		sort.Slice(ready, func(i, j int) bool {
-			return ready[i].PackageID < ready[j].PackageID
+			return less(ready[i], ready[j])
 		})
 	}
 
 	if len(result) != len(indegree) {
-		cycle := findCycle(graph)
-
-		var names []string
-		for _, m := range cycle {
-			names = append(names, string(m.PackageID))
-		}
-
+		cycles := FindCycles(mods)
 		return nil, fmt.Errorf(
-			"cycle detected in dependency graph: %s",
-			strings.Join(names, " -> "),
+			"%d cycles detected in dependency graph:\n%s",
+			len(cycles), describeCycles(cycles),
 		)
 	}
 
 	return result, nil
 }
 
-func SortMods(mods []*Mod) ([]*Mod, error) {
-	early := []*Mod{}
-	mid := make([]*Mod, 0, len(mods))
-	late := []*Mod{}
+const (
+	layerTop = iota
+	layerMiddle
+	layerBottom
+)
+
+var layerNames = []string{"top", "middle", "bottom"}
 
+// which SortMods layer each mod ends up in, call after LinkMods. official mods are always in the top layer,
+// along with anything they have to load after (like Harmony, which loads before Core)
+func Layers(mods []*Mod, rules Rules) map[*Mod]int {
+	layers := map[*Mod]int{}
 	for _, mod := range mods {
-		if slices.Contains(EarlyLoaders, string(mod.PackageID)) {
-			early = append(early, mod)
-			continue
+		tier, ok := rules.Tier(mod.PackageID)
+		switch {
+		case mod.Source == ModSourceOfficial || (ok && tier.Kind == RuleLoadTop):
+			layers[mod] = layerTop
+		case ok && tier.Kind == RuleLoadBottom:
+			layers[mod] = layerBottom
+		default:
+			layers[mod] = layerMiddle
 		}
-		if slices.Contains(LateLoaders, string(mod.PackageID)) {
-			late = append(late, mod)
+	}
+	for _, mod := range mods {
+		if mod.Source != ModSourceOfficial {
 			continue
 		}
-		mid = append(mid, mod)
+		for _, target := range mod.LoadAfter {
+			if layers[target] == layerMiddle {
+				layers[target] = layerTop
+			}
+		}
 	}
+	return layers
+}
 
-	sorted := make([]*Mod, 0, len(mods))
-
-	earlySorted, err := SortLayer(early)
-	if err != nil {
-		return nil, err
-	}
-	midSorted, err := SortLayer(mid)
-	if err != nil {
-		return nil, err
+// LoadAfter edges from a mod to one in a later layer, which the layer order makes impossible to honour
+func layerConflicts(mods []*Mod, layers map[*Mod]int) []string {
+	conflicts := []string{}
+	for _, mod := range mods {
+		for _, target := range mod.LoadAfter {
+			if layers[target] <= layers[mod] {
+				continue
+			}
+			for _, origin := range mod.LoadAfterOrigins[target] {
+				conflicts = append(conflicts, fmt.Sprintf(
+					"  %s, but it is in the %s layer and %s is in the %s layer",
+					DescribeEdge(mod, target, origin),
+					layerNames[layers[mod]], target.PackageID, layerNames[layers[target]],
+				))
+			}
+		}
 	}
-	lateSorted, err := SortLayer(late)
-	if err != nil {
-		return nil, err
+	return conflicts
+}
+
+func SortMods(mods []*Mod, rules Rules, order []PackageID) ([]*Mod, error) {
+	layers := Layers(mods, rules)
+	if conflicts := layerConflicts(mods, layers); len(conflicts) > 0 {
+		return nil, fmt.Errorf(
+			"%d load order rules contradict the load-top and load-bottom layers:\n%s\nchange the layers or suppress the rule in rules.toml",
+			len(conflicts), strings.Join(conflicts, "\n"),
+		)
 	}
 
-	sorted = append(sorted, earlySorted...)
-	sorted = append(sorted, midSorted...)
-	sorted = append(sorted, lateSorted...)
+	byLayer := make([][]*Mod, len(layerNames))
+	for _, mod := range mods {
+		byLayer[layers[mod]] = append(byLayer[layers[mod]], mod)
+	}
 
+	sorted := make([]*Mod, 0, len(mods))
+	for _, layer := range byLayer {
+		layerSorted, err := SortLayer(layer, order)
+		if err != nil {
+			return nil, err
+		}
+		sorted = append(sorted, layerSorted...)
+	}
 	return sorted, nil
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func sortedPids(mods []*Mod) []PackageID {
+	pids := []PackageID{}
+	for _, mod := range mods {
+		pids = append(pids, mod.PackageID)
+	}
+	return pids
+}
+
+func TestSortModsOfficialFirst(t *testing.T) {
+	core := &Mod{PackageID: CorePackageID, Source: ModSourceOfficial}
+	top := &Mod{PackageID: "top.mod", Source: ModSourceLocal}
+	mid := &Mod{PackageID: "mid.mod", Source: ModSourceLocal}
+	bottom := &Mod{PackageID: "bottom.mod", Source: ModSourceLocal}
+	for _, mod := range []*Mod{top, mid, bottom} {
+		linkAfter(mod, core, OriginCore)
+	}
+	rules := Rules{{Kind: RuleLoadTop, Mod: "top.mod"}, {Kind: RuleLoadBottom, Mod: "bottom.mod"}, {Kind: RuleLoadTop, Mod: CorePackageID}}
+
+	sorted, err := SortMods([]*Mod{bottom, mid, top, core}, rules, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PackageID{CorePackageID, "top.mod", "mid.mod", "bottom.mod"}
+	if got := sortedPids(sorted); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSortModsLayerConflict(t *testing.T) {
+	top := &Mod{PackageID: "top.mod", Source: ModSourceLocal}
+	mid := &Mod{PackageID: "mid.mod", Source: ModSourceLocal}
+	linkAfter(top, mid, OriginLoadAfter)
+	rules := Rules{{Kind: RuleLoadTop, Mod: "top.mod"}}
+
+	if _, err := SortMods([]*Mod{top, mid}, rules, nil); err == nil {
+		t.Error("top.mod loading after mid.mod should conflict with it being in the top layer")
+	}
+}
+
+func TestSortModsEdgesIntoEarlierLayers(t *testing.T) {
+	top := &Mod{PackageID: "top.mod", Source: ModSourceLocal}
+	a := &Mod{PackageID: "a.mod", Source: ModSourceLocal}
+	b := &Mod{PackageID: "b.mod", Source: ModSourceLocal}
+	linkAfter(a, top, OriginLoadAfter)
+	linkAfter(a, b, OriginLoadAfter)
+	rules := Rules{{Kind: RuleLoadTop, Mod: "top.mod"}}
+
+	sorted, err := SortMods([]*Mod{a, b, top}, rules, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PackageID{"top.mod", "b.mod", "a.mod"}
+	if got := sortedPids(sorted); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSortModsLoadBeforeCore(t *testing.T) {
+	core := &Mod{PackageID: CorePackageID, Source: ModSourceOfficial}
+	harmony := &Mod{PackageID: "brrainz.harmony", Source: ModSourceSteam}
+	top := &Mod{PackageID: "top.mod", Source: ModSourceLocal}
+	mid := &Mod{PackageID: "mid.mod", Source: ModSourceLocal}
+	linkAfter(core, harmony, OriginLoadBefore)
+	linkAfter(top, core, OriginCore)
+	linkAfter(mid, core, OriginCore)
+	linkAfter(mid, harmony, OriginLoadAfter)
+	rules := Rules{{Kind: RuleLoadTop, Mod: "top.mod"}}
+
+	sorted, err := SortMods([]*Mod{mid, top, core, harmony}, rules, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PackageID{"brrainz.harmony", CorePackageID, "top.mod", "mid.mod"}
+	if got := sortedPids(sorted); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSortModsUserTierOverridesCommunity(t *testing.T) {
+	a := &Mod{PackageID: "a.mod", Source: ModSourceLocal}
+	b := &Mod{PackageID: "b.mod", Source: ModSourceLocal}
+	c := &Mod{PackageID: "c.mod", Source: ModSourceLocal}
+	rules := Rules{
+		{Kind: RuleLoadTop, Mod: "a.mod", Source: communityRulesFile},
+		{Kind: RuleLoadBottom, Mod: "a.mod", Source: userRulesFile},
+		{Kind: RuleLoadTop, Mod: "c.mod", Source: userRulesFile},
+		{Kind: RuleLoadBottom, Mod: "c.mod", Source: communityRulesFile},
+	}
+
+	sorted, err := SortMods([]*Mod{a, b, c}, rules, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PackageID{"c.mod", "b.mod", "a.mod"}
+	if got := sortedPids(sorted); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	applied := rules.Applied([]*Mod{a, b, c})
+	if len(applied) != 2 || applied[0] != rules[1] || applied[1] != rules[2] {
+		t.Errorf("applied %v, want only the rules.toml tiers", applied)
+	}
+}
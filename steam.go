@@ -11,6 +11,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"strings"
 )
 
 type SteamResponseWrapper struct {
@@ -61,6 +62,10 @@ const rimworldAppID = "294100"
 
 var ErrSteamCMDMIA = errors.New("SteamCMD did not create the mod folder after install")
 
+func SteamModPath(config Config, id SteamID) string {
+	return filepath.Join(config.SteamModSrc, strconv.Itoa(int(id)))
+}
+
 func SteamCMDInstall(config Config, ids []SteamID) error {
 	args := []string{}
 	args = append(args, "+logon", "anonymous")
@@ -84,19 +89,36 @@ func SteamCMDInstall(config Config, ids []SteamID) error {
 		}
 	}
 
-	succeeded := true
+	missing := []SteamID{}
 	for _, id := range ids {
-		modPath := filepath.Join(config.SteamModSrc, strconv.Itoa(int(id)))
+		modPath := SteamModPath(config, id)
 		if _, err := os.Stat(modPath); errors.Is(err, os.ErrNotExist) {
-			succeeded = false
-			fmt.Printf("After running SteamCMD, mod %d could not be found", id)
+			missing = append(missing, id)
+			fmt.Printf("After running SteamCMD, mod %d could not be found\n", id)
 		}
 	}
 
-	if succeeded {
+	if len(missing) == 0 {
 		return nil
 	}
 
+	return &SteamCMDMissingError{IDs: missing}
+}
+
+// the items of a SteamCMDInstall batch that didn't download, the rest did
+type SteamCMDMissingError struct {
+	IDs []SteamID
+}
+
+func (err *SteamCMDMissingError) Error() string {
+	ids := []string{}
+	for _, id := range err.IDs {
+		ids = append(ids, strconv.Itoa(int(id)))
+	}
+	return fmt.Sprintf("%s: %s", ErrSteamCMDMIA, strings.Join(ids, ", "))
+}
+
+func (err *SteamCMDMissingError) Unwrap() error {
 	return ErrSteamCMDMIA
 }
 
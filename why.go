@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// shortest chain of LoadAfter edges from -> ... -> to, meaning from has to load after to.
+// nil if no such chain exists
+func LoadAfterChain(from *Mod, to *Mod) []*Mod {
+	parent := map[*Mod]*Mod{from: nil}
+	queue := []*Mod{from}
+	for len(queue) > 0 {
+		mod := queue[0]
+		queue = queue[1:]
+		if mod == to {
+			chain := []*Mod{}
+			for x := to; x != nil; x = parent[x] {
+				chain = append([]*Mod{x}, chain...)
+			}
+			return chain
+		}
+		for _, next := range mod.LoadAfter {
+			if _, seen := parent[next]; seen {
+				continue
+			}
+			parent[next] = mod
+			queue = append(queue, next)
+		}
+	}
+	return nil
+}
+
+func describeChain(chain []*Mod) string {
+	lines := []string{}
+	for i := 0; i < len(chain)-1; i++ {
+		mod, target := chain[i], chain[i+1]
+		for _, origin := range mod.LoadAfterOrigins[target] {
+			lines = append(lines, "  "+DescribeEdge(mod, target, origin))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func ExplainOrder(a *Mod, b *Mod, mods []*Mod, rules Rules) string {
+	layers := Layers(mods, rules)
+	layerA, layerB := layers[a], layers[b]
+	if layerA != layerB {
+		first, second := a, b
+		if layerB < layerA {
+			first, second = b, a
+		}
+		return fmt.Sprintf(
+			"%s loads before %s because it is in the %s layer and %s is in the %s layer",
+			first.PackageID, second.PackageID,
+			layerNames[layers[first]], second.PackageID, layerNames[layers[second]],
+		)
+	}
+	if chain := LoadAfterChain(a, b); chain != nil {
+		return fmt.Sprintf("%s loads after %s:\n%s", a.PackageID, b.PackageID, describeChain(chain))
+	}
+	if chain := LoadAfterChain(b, a); chain != nil {
+		return fmt.Sprintf("%s loads after %s:\n%s", b.PackageID, a.PackageID, describeChain(chain))
+	}
+	return fmt.Sprintf(
+		"no rule orders %s and %s, their relative order is only decided by the tie-break (alphabetical, or --keep-order)",
+		a.PackageID, b.PackageID,
+	)
+}
+
+func ExplainMod(mod *Mod, mods []*Mod, rules Rules) string {
+	lines := []string{fmt.Sprintf("%s is in the %s layer", mod.PackageID, layerNames[Layers(mods, rules)[mod]])}
+	for _, target := range mod.LoadAfter {
+		for _, origin := range mod.LoadAfterOrigins[target] {
+			lines = append(lines, "  "+DescribeEdge(mod, target, origin))
+		}
+	}
+	for _, other := range mods {
+		for _, origin := range other.LoadAfterOrigins[mod] {
+			lines = append(lines, "  "+DescribeEdge(other, mod, origin))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
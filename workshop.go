@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// an entry of WorkshopItemsInstalled in Steam's appworkshop manifest
+type InstalledItem struct {
+	Size int64
+	// workshop update time of the copy that is installed, not of the latest upload
+	TimeUpdated int64
+	Manifest    string
+}
+
+// Steam keeps track of downloaded items in steamapps/workshop/appworkshop_294100.acf,
+// two folders up from the content folder SteamModSrc points at
+func WorkshopManifestPath(config Config) string {
+	return filepath.Join(config.SteamModSrc, "..", "..", "appworkshop_"+rimworldAppID+".acf")
+}
+
+func ReadWorkshopManifest(config Config) (map[SteamID]InstalledItem, error) {
+	data, err := os.ReadFile(WorkshopManifestPath(config))
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseVDF(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", WorkshopManifestPath(config), err)
+	}
+	workshop, _ := root["AppWorkshop"].(map[string]any)
+	installed, _ := workshop["WorkshopItemsInstalled"].(map[string]any)
+
+	items := map[SteamID]InstalledItem{}
+	for key, value := range installed {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			continue
+		}
+		fields, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		field := func(name string) string {
+			s, _ := fields[name].(string)
+			return s
+		}
+		size, _ := strconv.ParseInt(field("size"), 10, 64)
+		timeUpdated, _ := strconv.ParseInt(field("timeupdated"), 10, 64)
+		items[SteamID(id)] = InstalledItem{Size: size, TimeUpdated: timeUpdated, Manifest: field("manifest")}
+	}
+	return items, nil
+}
+
+// Valve's KeyValues text format: quoted keys followed by a quoted value or a { } block
+func parseVDF(data string) (map[string]any, error) {
+	pos := 0
+	next := func() (string, bool, error) {
+		for pos < len(data) {
+			switch {
+			case strings.ContainsRune(" \t\r\n", rune(data[pos])):
+				pos++
+			case strings.HasPrefix(data[pos:], "//"):
+				for pos < len(data) && data[pos] != '\n' {
+					pos++
+				}
+			case data[pos] == '{' || data[pos] == '}':
+				pos++
+				return data[pos-1 : pos], false, nil
+			case data[pos] == '"':
+				var token strings.Builder
+				for pos++; pos < len(data) && data[pos] != '"'; pos++ {
+					if data[pos] == '\\' && pos+1 < len(data) {
+						pos++
+					}
+					token.WriteByte(data[pos])
+				}
+				if pos >= len(data) {
+					return "", false, fmt.Errorf("unterminated string")
+				}
+				pos++
+				return token.String(), true, nil
+			default:
+				return "", false, fmt.Errorf("unexpected %q at offset %d", data[pos], pos)
+			}
+		}
+		return "", false, nil
+	}
+
+	var block func(nested bool) (map[string]any, error)
+	block = func(nested bool) (map[string]any, error) {
+		values := map[string]any{}
+		for {
+			key, quoted, err := next()
+			if err != nil {
+				return nil, err
+			}
+			if !quoted {
+				if key == "}" && nested {
+					return values, nil
+				}
+				if key == "" && !nested {
+					return values, nil
+				}
+				return nil, fmt.Errorf("unexpected %q at offset %d", key, pos)
+			}
+			value, quoted, err := next()
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case quoted:
+				values[key] = value
+			case value == "{":
+				values[key], err = block(true)
+				if err != nil {
+					return nil, err
+				}
+			default:
+				return nil, fmt.Errorf("missing value for %q", key)
+			}
+		}
+	}
+	return block(false)
+}
+
+// when Steam's manifest has no entry for a mod, the newest modification time of its files
+func newestModTime(path string) (int64, error) {
+	var newest time.Time
+	err := filepath.WalkDir(path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	return newest.Unix(), err
+}
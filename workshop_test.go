@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadWorkshopManifest(t *testing.T) {
+	// SteamModSrc is steamapps/workshop/content/294100, the manifest is in steamapps/workshop
+	steamapps := t.TempDir()
+	content := filepath.Join(steamapps, "workshop", "content", rimworldAppID)
+	if err := os.MkdirAll(content, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", "appworkshop_294100.acf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(steamapps, "workshop", "appworkshop_294100.acf"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := ReadWorkshopManifest(Config{SteamModSrc: content})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[SteamID]InstalledItem{
+		2009463077: {Size: 1018806, TimeUpdated: 1755188374, Manifest: "4321543812749130155"},
+		818773962:  {Size: 3512003, TimeUpdated: 1752356401, Manifest: "8855331213453340711"},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(items), len(want))
+	}
+	for id, item := range want {
+		if items[id] != item {
+			t.Errorf("%d = %+v, want %+v", id, items[id], item)
+		}
+	}
+}
+
+func TestParseVDFMalformed(t *testing.T) {
+	for _, data := range []string{`"a" {`, `"a"`, `"a" "b`, `}`, `"a" { "b" }`} {
+		if _, err := parseVDF(data); err == nil {
+			t.Errorf("no error for %q", data)
+		}
+	}
+}